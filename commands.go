@@ -0,0 +1,296 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "ipservice/internal/config"
+    "ipservice/internal/ipdb"
+    "ipservice/internal/server"
+)
+
+// commands 为命令行子命令，首个参数命中时执行对应命令而不启动 HTTP 服务。
+var commands = map[string]func(args []string) error{
+    "search": runSearch,
+    "diff":   runDiff,
+    "config": runConfig,
+    "apikey": runAPIKey,
+}
+
+// runSearch 按国家/区域反向检索地址区段，例如 `ipservice search -country 江苏 -area 移动`。
+func runSearch(args []string) error {
+    fs := flag.NewFlagSet("search", flag.ContinueOnError)
+    country := fs.String("country", "", "国家/省市关键字，按子串匹配")
+    area := fs.String("area", "", "运营商/区域关键字，按子串匹配")
+    page := fs.Int("page", 1, "页码，从 1 开始")
+    pageSize := fs.Int("size", 50, "每页条数，最多 1000")
+    asJSON := fs.Bool("json", false, "以 JSON 输出")
+    data := fs.String("data", "", "qqwry.dat 路径，默认读取服务配置")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    svc, err := openService(*data)
+    if err != nil {
+        return err
+    }
+    result, err := svc.Search(ipdb.SearchQuery{
+        Country:  *country,
+        Area:     *area,
+        Page:     *page,
+        PageSize: *pageSize,
+    })
+    if err != nil {
+        return err
+    }
+
+    if *asJSON {
+        type rangeJSON struct {
+            Start   string `json:"start"`
+            End     string `json:"end"`
+            Country string `json:"country"`
+            Area    string `json:"area"`
+        }
+        out := struct {
+            Total    int         `json:"total"`
+            Page     int         `json:"page"`
+            PageSize int         `json:"page_size"`
+            Ranges   []rangeJSON `json:"ranges"`
+            CIDRs    []string    `json:"cidrs"`
+        }{Total: result.Total, Page: result.Page, PageSize: result.PageSize, Ranges: []rangeJSON{}, CIDRs: result.CIDRs}
+        for _, r := range result.Ranges {
+            out.Ranges = append(out.Ranges, rangeJSON{r.StartIP(), r.EndIP(), r.Country, r.Area})
+        }
+        return printJSON(out)
+    }
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+    fmt.Fprintf(w, "起始\t结束\t国家\t区域\n")
+    for _, r := range result.Ranges {
+        fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.StartIP(), r.EndIP(), r.Country, r.Area)
+    }
+    if err := w.Flush(); err != nil {
+        return err
+    }
+    fmt.Printf("\n共 %d 条，第 %d 页（每页 %d 条）\n", result.Total, result.Page, result.PageSize)
+    for _, cidr := range result.CIDRs {
+        fmt.Println(cidr)
+    }
+    return nil
+}
+
+// runDiff 对比两个数据文件，例如 `ipservice diff old.dat new.dat`，便于在更新前审阅变化。
+func runDiff(args []string) error {
+    fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+    asJSON := fs.Bool("json", false, "以 JSON 输出")
+    limit := fs.Int("limit", 0, "最多输出的变化条数，0 表示不限制（统计信息不受影响）")
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "用法: ipservice diff [-json] [-limit N] <旧 qqwry.dat> <新 qqwry.dat>")
+        fs.PrintDefaults()
+    }
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+    if fs.NArg() != 2 {
+        fs.Usage()
+        return fmt.Errorf("需要提供新旧两个数据文件路径")
+    }
+
+    report, err := ipdb.DiffFiles(fs.Arg(0), fs.Arg(1))
+    if err != nil {
+        return err
+    }
+    entries := report.Entries
+    if *limit > 0 && len(entries) > *limit {
+        entries = entries[:*limit]
+    }
+
+    if *asJSON {
+        type entryJSON struct {
+            Kind   ipdb.DiffKind `json:"kind"`
+            Start  string        `json:"start"`
+            End    string        `json:"end"`
+            Before [2]string     `json:"before"`
+            After  [2]string     `json:"after"`
+        }
+        type summaryJSON struct {
+            OldVersion string `json:"old_version"`
+            NewVersion string `json:"new_version"`
+            OldRecords int    `json:"old_records"`
+            NewRecords int    `json:"new_records"`
+            Added      int    `json:"added"`
+            Removed    int    `json:"removed"`
+            Changed    int    `json:"changed"`
+            AddedIPs   uint64 `json:"added_ips"`
+            RemovedIPs uint64 `json:"removed_ips"`
+            ChangedIPs uint64 `json:"changed_ips"`
+        }
+        out := struct {
+            Summary summaryJSON `json:"summary"`
+            Entries []entryJSON `json:"entries"`
+        }{Summary: summaryJSON(report.Summary), Entries: []entryJSON{}}
+        for _, e := range entries {
+            out.Entries = append(out.Entries, entryJSON{
+                Kind:   e.Kind,
+                Start:  e.StartIP(),
+                End:    e.EndIP(),
+                Before: [2]string{e.OldCountry, e.OldArea},
+                After:  [2]string{e.NewCountry, e.NewArea},
+            })
+        }
+        return printJSON(out)
+    }
+
+    sum := report.Summary
+    fmt.Printf("旧版本: %s（%d 条记录）\n", sum.OldVersion, sum.OldRecords)
+    fmt.Printf("新版本: %s（%d 条记录）\n", sum.NewVersion, sum.NewRecords)
+    fmt.Printf("新增 %d 段 / %d 个地址，移除 %d 段 / %d 个地址，变更 %d 段 / %d 个地址\n\n",
+        sum.Added, sum.AddedIPs, sum.Removed, sum.RemovedIPs, sum.Changed, sum.ChangedIPs)
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+    for _, e := range entries {
+        switch e.Kind {
+        case ipdb.DiffAdded:
+            fmt.Fprintf(w, "+\t%s - %s\t\t%s %s\n", e.StartIP(), e.EndIP(), e.NewCountry, e.NewArea)
+        case ipdb.DiffRemoved:
+            fmt.Fprintf(w, "-\t%s - %s\t%s %s\t\n", e.StartIP(), e.EndIP(), e.OldCountry, e.OldArea)
+        default:
+            fmt.Fprintf(w, "~\t%s - %s\t%s %s\t→ %s %s\n", e.StartIP(), e.EndIP(), e.OldCountry, e.OldArea, e.NewCountry, e.NewArea)
+        }
+    }
+    if err := w.Flush(); err != nil {
+        return err
+    }
+    if len(entries) < len(report.Entries) {
+        fmt.Printf("\n（仅显示前 %d 条，共 %d 条变化）\n", len(entries), len(report.Entries))
+    }
+    return nil
+}
+
+// runConfig 处理配置相关子命令，目前支持 `ipservice config print [-config path] [-format yaml|toml|json]`，
+// 输出默认值、配置文件与环境变量合并后的生效配置。
+func runConfig(args []string) error {
+    if len(args) == 0 || args[0] != "print" {
+        return errors.New("用法: config print [-config path] [-format yaml|toml|json]")
+    }
+    fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+    path := fs.String("config", "", "配置文件路径（YAML 或 TOML），默认读取 IP_API_CONFIG")
+    format := fs.String("format", "yaml", "输出格式：yaml、toml 或 json")
+    if err := fs.Parse(args[1:]); err != nil {
+        return err
+    }
+
+    cfg, err := config.Read(*path)
+    if err != nil {
+        return err
+    }
+    return cfg.Encode(os.Stdout, *format)
+}
+
+// runAPIKey 管理密钥库：`apikey create` 生成新密钥并仅输出一次明文，`apikey list` 列出已有密钥。
+func runAPIKey(args []string) error {
+    const usage = "用法: apikey create -id ID -scopes lookup,batch [-expires 720h] [-rate N] [-burst N] [-quota N] [-file keys.json] | apikey list [-file keys.json]"
+    if len(args) == 0 {
+        return errors.New(usage)
+    }
+    fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+    file := fs.String("file", "", "密钥库文件，默认读取服务配置中的 auth.key_file")
+    openStore := func() (*server.KeyStore, error) {
+        path := *file
+        if path == "" {
+            cfg, err := config.Read("")
+            if err != nil {
+                return nil, fmt.Errorf("配置加载失败: %w", err)
+            }
+            path = cfg.Auth.KeyFile
+        }
+        return server.LoadKeyStore(path)
+    }
+
+    switch args[0] {
+    case "create":
+        id := fs.String("id", "", "密钥标识，如调用方团队名")
+        scopes := fs.String("scopes", server.ScopeLookup, "逗号分隔的权限：lookup、batch、admin")
+        expires := fs.Duration("expires", 0, "有效期，如 720h；0 表示永不过期")
+        rate := fs.Float64("rate", 0, "专属限流速率（每秒请求数），0 表示沿用全局配置")
+        burst := fs.Int("burst", 0, "专属突发请求数，0 表示沿用全局配置")
+        quota := fs.Int64("quota", 0, "专属每日配额，0 表示沿用全局配置")
+        if err := fs.Parse(args[1:]); err != nil {
+            return err
+        }
+        store, err := openStore()
+        if err != nil {
+            return err
+        }
+        key := server.APIKey{
+            ID:         *id,
+            Scopes:     strings.Split(*scopes, ","),
+            Rate:       *rate,
+            Burst:      *burst,
+            DailyQuota: *quota,
+        }
+        if *expires > 0 {
+            at := time.Now().Add(*expires).UTC().Truncate(time.Second)
+            key.ExpiresAt = &at
+        }
+        plain, err := store.Add(key)
+        if err != nil {
+            return err
+        }
+        fmt.Println(plain)
+        fmt.Fprintln(os.Stderr, "请妥善保存上述密钥，密钥库仅保存其摘要，无法再次查看")
+        return nil
+    case "list":
+        if err := fs.Parse(args[1:]); err != nil {
+            return err
+        }
+        store, err := openStore()
+        if err != nil {
+            return err
+        }
+        w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(w, "ID\tSCOPES\tEXPIRES\tSTATUS")
+        now := time.Now()
+        for _, k := range store.Keys() {
+            expires, status := "-", "active"
+            if k.ExpiresAt != nil {
+                expires = k.ExpiresAt.Format(time.RFC3339)
+            }
+            switch {
+            case k.Disabled:
+                status = "disabled"
+            case k.Expired(now):
+                status = "expired"
+            }
+            fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.ID, strings.Join(k.Scopes, ","), expires, status)
+        }
+        return w.Flush()
+    default:
+        return errors.New(usage)
+    }
+}
+
+// openService 加载数据文件；未显式指定路径时沿用服务配置（含自动下载）。
+func openService(path string) (*ipdb.Service, error) {
+    if path == "" {
+        cfg, err := config.Load("")
+        if err != nil {
+            return nil, fmt.Errorf("配置加载失败: %w", err)
+        }
+        path = cfg.Data.Path
+    }
+    return ipdb.NewService(path)
+}
+
+func printJSON(v any) error {
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    enc.SetEscapeHTML(false)
+    return enc.Encode(v)
+}
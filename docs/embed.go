@@ -0,0 +1,9 @@
+// Package docs 内置 API 使用说明，供 /docs 页面渲染，运行时无需依赖工作目录中的 docs 目录。
+package docs
+
+import "embed"
+
+// FS 包含本目录下的全部 Markdown 文档。
+//
+//go:embed *.md
+var FS embed.FS
@@ -5,39 +5,219 @@ import (
     "fmt"
     "os"
     "path/filepath"
+    "strconv"
     "strings"
+    "time"
 )
 
 const (
-    envListen     = "IP_API_LISTEN"
-    envQQwryPath  = "IP_API_QQWRY_PATH"
-    envQQwryURL   = "IP_API_QQWRY_URL"
-    envAutoFetch  = "IP_API_AUTO_FETCH"
-
-    defaultListen    = ":8080"
-    defaultData      = "qqwry.dat"
-    defaultDataURL   = "https://github.com/metowolf/qqwry.dat/releases/latest/download/qqwry.dat"
+    envConfig         = "IP_API_CONFIG"
+    envListen         = "IP_API_LISTEN"
+    envQQwryPath      = "IP_API_QQWRY_PATH"
+    envQQwryURL       = "IP_API_QQWRY_URL"
+    envAutoFetch      = "IP_API_AUTO_FETCH"
+    envSkipNonPublic  = "IP_API_SKIP_NON_PUBLIC"
+    envQQwrySHA256    = "IP_API_QQWRY_SHA256"
+    envQQwrySHA256URL = "IP_API_QQWRY_SHA256_URL"
+    envQQwryPubKey    = "IP_API_QQWRY_PUBKEY"
+    envQQwrySigURL    = "IP_API_QQWRY_SIG_URL"
+    envQQwryMirrors   = "IP_API_QQWRY_MIRRORS"
+    envFetchRetries   = "IP_API_FETCH_RETRIES"
+    envFetchTimeout   = "IP_API_FETCH_TIMEOUT"
+    envUpdateInterval = "IP_API_UPDATE_INTERVAL"
+    envQQwryFormat    = "IP_API_QQWRY_FORMAT"
+    envCopywriteURL   = "IP_API_CZ88_COPYWRITE_URL"
+    envTrustedProxies = "IP_API_TRUSTED_PROXIES"
+    envBatchLimit     = "IP_API_BATCH_LIMIT"
+    envCompat         = "IP_API_COMPAT"
+    envWebDir         = "IP_API_WEB_DIR"
+    envRateLimit      = "IP_API_RATE_LIMIT"
+    envRateLimitRate  = "IP_API_RATE_LIMIT_RATE"
+    envRateLimitBurst = "IP_API_RATE_LIMIT_BURST"
+    envDailyQuota     = "IP_API_DAILY_QUOTA"
+    envQuotaFile      = "IP_API_QUOTA_FILE"
+    envAuth           = "IP_API_AUTH"
+    envKeyFile        = "IP_API_KEY_FILE"
+    envAllowAnonymous = "IP_API_ALLOW_ANONYMOUS"
+    envCORS           = "IP_API_CORS"
+    envCORSOrigins    = "IP_API_CORS_ORIGINS"
+    envJSONP          = "IP_API_JSONP"
+
+    defaultListen       = ":8080"
+    defaultData         = "qqwry.dat"
+    defaultDataURL      = "https://github.com/metowolf/qqwry.dat/releases/latest/download/qqwry.dat"
+    defaultFetchRetries = 3
+    defaultFetchTimeout = 10 * time.Minute
 )
 
-// Config 表示服务运行时所需的核心配置。
+// Config 表示服务运行时的完整配置，可由配置文件提供，环境变量优先级更高。
 type Config struct {
-    ListenAddr string
-    QQWryPath  string
+    Server    ServerConfig    `yaml:"server" toml:"server" json:"server"`
+    Data      DataConfig      `yaml:"data" toml:"data" json:"data"`
+    Update    UpdateConfig    `yaml:"update" toml:"update" json:"update"`
+    RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit"`
+    Auth      AuthConfig      `yaml:"auth" toml:"auth" json:"auth"`
+    CORS      CORSConfig      `yaml:"cors" toml:"cors" json:"cors"`
 }
 
-// Load 从环境变量读取配置并补全默认值，同时校验关键依赖是否存在。
-func Load() (*Config, error) {
-    cfg := &Config{
-        ListenAddr: getOrDefault(envListen, defaultListen),
-        QQWryPath:  resolvePath(getOrDefault(envQQwryPath, defaultData)),
+// ServerConfig 为 HTTP 服务相关配置。
+type ServerConfig struct {
+    Listen            string   `yaml:"listen" toml:"listen" json:"listen"`
+    ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout" json:"read_header_timeout"`
+    ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout"`
+    WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout"`
+    IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout"`
+    ShutdownTimeout   Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout"`
+
+    // TrustedProxies 为可信代理的 IP 或网段，仅信任来自这些地址的 X-Forwarded-For；为空时沿用 Gin 默认行为
+    TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" json:"trusted_proxies"`
+
+    BatchLimit int `yaml:"batch_limit" toml:"batch_limit" json:"batch_limit"` // 批量查询单次最多 IP 数
+    CIDRLimit  int `yaml:"cidr_limit" toml:"cidr_limit" json:"cidr_limit"`    // 网段查询单次最多返回记录数
+
+    // Compat 为启用的兼容接口分组：ip-api、ipinfo、taobao、pconline，挂载在 /compat/<名称> 下
+    Compat []string `yaml:"compat" toml:"compat" json:"compat"`
+
+    // WebDir 为页面资源覆盖目录，其中的 templates/、static/、docs/ 同名文件优先于内置版本；为空时仅使用内置资源
+    WebDir string `yaml:"web_dir" toml:"web_dir" json:"web_dir"`
+}
+
+// DataConfig 为数据文件的位置、来源与完整性校验配置。
+type DataConfig struct {
+    Path      string `yaml:"path" toml:"path" json:"path"`
+    AutoFetch bool   `yaml:"auto_fetch" toml:"auto_fetch" json:"auto_fetch"`
+
+    // SkipNonPublic 为 true 时，私有/回环/组播等非公网地址不再查询 qqwry
+    SkipNonPublic bool `yaml:"skip_non_public" toml:"skip_non_public" json:"skip_non_public"`
+
+    Format       string   `yaml:"format" toml:"format" json:"format"`
+    URL          string   `yaml:"url" toml:"url" json:"url"`
+    Mirrors      []string `yaml:"mirrors" toml:"mirrors" json:"mirrors"`
+    CopywriteURL string   `yaml:"copywrite_url" toml:"copywrite_url" json:"copywrite_url"`
+
+    SHA256       string `yaml:"sha256" toml:"sha256" json:"sha256"`
+    SHA256URL    string `yaml:"sha256_url" toml:"sha256_url" json:"sha256_url"`
+    PublicKey    string `yaml:"public_key" toml:"public_key" json:"public_key"`
+    SignatureURL string `yaml:"signature_url" toml:"signature_url" json:"signature_url"`
+}
+
+// UpdateConfig 为下载重试与定期更新配置；Interval 为 0 时不检查远端更新。
+type UpdateConfig struct {
+    Interval Duration `yaml:"interval" toml:"interval" json:"interval"`
+    Retries  int      `yaml:"retries" toml:"retries" json:"retries"`
+    Timeout  Duration `yaml:"timeout" toml:"timeout" json:"timeout"`
+}
+
+// RateLimitConfig 为查询接口的令牌桶限流与每日配额配置，按 API Key 或客户端 IP 分别计数。
+type RateLimitConfig struct {
+    Enabled    bool    `yaml:"enabled" toml:"enabled" json:"enabled"`
+    Rate       float64 `yaml:"rate" toml:"rate" json:"rate"`                      // 每秒补充的令牌数
+    Burst      int     `yaml:"burst" toml:"burst" json:"burst"`                   // 允许的瞬时突发请求数
+    DailyQuota int64   `yaml:"daily_quota" toml:"daily_quota" json:"daily_quota"` // 每日请求上限，0 表示不限
+    QuotaFile  string  `yaml:"quota_file" toml:"quota_file" json:"quota_file"`    // 配额计数持久化文件，为空时仅保存在内存中
+}
+
+// AuthConfig 为 API Key 认证配置；密钥库文件仅保存密钥的 SHA-256 摘要。
+type AuthConfig struct {
+    Enabled        bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+    KeyFile        string `yaml:"key_file" toml:"key_file" json:"key_file"`
+    AllowAnonymous bool   `yaml:"allow_anonymous" toml:"allow_anonymous" json:"allow_anonymous"` // 允许未携带密钥的请求访问查询接口
+}
+
+// CORSConfig 为浏览器跨域访问配置；AllowOrigins 支持 "*" 与 "https://*.example.com" 形式的子域通配。
+type CORSConfig struct {
+    Enabled          bool     `yaml:"enabled" toml:"enabled" json:"enabled"`
+    AllowOrigins     []string `yaml:"allow_origins" toml:"allow_origins" json:"allow_origins"`
+    AllowMethods     []string `yaml:"allow_methods" toml:"allow_methods" json:"allow_methods"`
+    AllowHeaders     []string `yaml:"allow_headers" toml:"allow_headers" json:"allow_headers"`
+    ExposeHeaders    []string `yaml:"expose_headers" toml:"expose_headers" json:"expose_headers"`
+    AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" json:"allow_credentials"`
+    MaxAge           Duration `yaml:"max_age" toml:"max_age" json:"max_age"`
+    JSONP            bool     `yaml:"jsonp" toml:"jsonp" json:"jsonp"` // 允许 GET 请求通过 callback 参数获取 JSONP 响应
+}
+
+// Default 返回内置默认配置。
+func Default() *Config {
+    return &Config{
+        Server: ServerConfig{
+            Listen:            defaultListen,
+            ReadHeaderTimeout: Duration(5 * time.Second),
+            ReadTimeout:       Duration(10 * time.Second),
+            WriteTimeout:      Duration(15 * time.Second),
+            IdleTimeout:       Duration(60 * time.Second),
+            ShutdownTimeout:   Duration(10 * time.Second),
+            BatchLimit:        100,
+            CIDRLimit:         10000,
+        },
+        Data: DataConfig{
+            Path:      defaultData,
+            AutoFetch: true,
+            Format:    FormatDat,
+        },
+        Update: UpdateConfig{
+            Retries: defaultFetchRetries,
+            Timeout: Duration(defaultFetchTimeout),
+        },
+        RateLimit: RateLimitConfig{
+            Rate:      10,
+            Burst:     20,
+            QuotaFile: "quota.json",
+        },
+        Auth: AuthConfig{
+            KeyFile:        "keys.json",
+            AllowAnonymous: true,
+        },
+        CORS: CORSConfig{
+            AllowOrigins:  []string{"*"},
+            AllowMethods:  []string{"GET", "POST", "OPTIONS"},
+            AllowHeaders:  []string{"Content-Type", "X-API-Key"},
+            ExposeHeaders: []string{
+                "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+                "X-RateLimit-Quota-Limit", "X-RateLimit-Quota-Remaining", "X-RateLimit-Quota-Reset",
+                "Retry-After", "X-Request-ID",
+            },
+            MaxAge:        Duration(12 * time.Hour),
+        },
     }
+}
 
-    // 若启用自动获取，则在校验前尝试从远端下载缺失的数据文件
-    if isTruthy(getOrDefault(envAutoFetch, "true")) {
-        if err := ensureQQWryFile(cfg.QQWryPath, getOrDefault(envQQwryURL, defaultDataURL)); err != nil {
+// Load 合并配置并校验，必要时下载缺失的数据文件。path 为空时读取 IP_API_CONFIG 指定的配置文件。
+func Load(path string) (*Config, error) {
+    cfg, err := Read(path)
+    if err != nil {
+        return nil, err
+    }
+
+    // 若启用自动获取，则在检查前尝试从远端下载缺失的数据文件
+    if cfg.Data.AutoFetch {
+        if err := ensureQQWryFile(cfg.Data.Path, cfg.Source()); err != nil {
+            return nil, err
+        }
+    }
+    if _, err := os.Stat(cfg.Data.Path); err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            return nil, fmt.Errorf("未找到qqwry.dat: %s", cfg.Data.Path)
+        }
+        return nil, fmt.Errorf("无法读取qqwry.dat: %w", err)
+    }
+    return cfg, nil
+}
+
+// Read 按“默认值 < 配置文件 < 环境变量”的优先级合并配置并校验，不访问数据文件。
+func Read(path string) (*Config, error) {
+    cfg := Default()
+    if path == "" {
+        path = os.Getenv(envConfig)
+    }
+    if path != "" {
+        if err := decodeFile(path, cfg); err != nil {
             return nil, err
         }
     }
+    if err := cfg.applyEnv(); err != nil {
+        return nil, err
+    }
+    cfg.applyDerived()
 
     if err := cfg.Validate(); err != nil {
         return nil, err
@@ -45,32 +225,171 @@ func Load() (*Config, error) {
     return cfg, nil
 }
 
-// Validate 对配置进行完整性校验。
-func (c *Config) Validate() error {
-    if c.ListenAddr == "" {
-        return errors.New("监听地址不能为空")
-    }
-    if c.QQWryPath == "" {
-        return errors.New("qqwry.dat 路径不能为空")
+// Source 返回数据文件的下载来源与校验要求。
+func (c *Config) Source() Source {
+    return Source{
+        URL:          c.Data.URL,
+        Mirrors:      c.Data.Mirrors,
+        Format:       c.Data.Format,
+        CopywriteURL: c.Data.CopywriteURL,
+        Retries:      c.Update.Retries,
+        Timeout:      time.Duration(c.Update.Timeout),
+        SHA256:       c.Data.SHA256,
+        SHA256URL:    c.Data.SHA256URL,
+        PublicKey:    c.Data.PublicKey,
+        SignatureURL: c.Data.SignatureURL,
     }
-    if _, err := os.Stat(c.QQWryPath); err != nil {
-        if errors.Is(err, os.ErrNotExist) {
-            return fmt.Errorf("未找到qqwry.dat: %s", c.QQWryPath)
+}
+
+// applyEnv 使用已设置的环境变量覆盖配置。
+func (c *Config) applyEnv() error {
+    setString(&c.Server.Listen, envListen)
+    setString(&c.Data.Path, envQQwryPath)
+    setString(&c.Data.Format, envQQwryFormat)
+    setString(&c.Data.URL, envQQwryURL)
+    setString(&c.Data.CopywriteURL, envCopywriteURL)
+    setString(&c.Data.SHA256, envQQwrySHA256)
+    setString(&c.Data.SHA256URL, envQQwrySHA256URL)
+    setString(&c.Data.PublicKey, envQQwryPubKey)
+    setString(&c.Data.SignatureURL, envQQwrySigURL)
+    setList(&c.Data.Mirrors, envQQwryMirrors)
+    setList(&c.Server.TrustedProxies, envTrustedProxies)
+    setList(&c.Server.Compat, envCompat)
+    setString(&c.Server.WebDir, envWebDir)
+    setBool(&c.Data.AutoFetch, envAutoFetch)
+    setBool(&c.Data.SkipNonPublic, envSkipNonPublic)
+    setBool(&c.RateLimit.Enabled, envRateLimit)
+    setString(&c.RateLimit.QuotaFile, envQuotaFile)
+    setBool(&c.Auth.Enabled, envAuth)
+    setString(&c.Auth.KeyFile, envKeyFile)
+    setBool(&c.Auth.AllowAnonymous, envAllowAnonymous)
+    setBool(&c.CORS.Enabled, envCORS)
+    setList(&c.CORS.AllowOrigins, envCORSOrigins)
+    setBool(&c.CORS.JSONP, envJSONP)
+
+    for _, err := range []error{
+        setInt(&c.Update.Retries, envFetchRetries),
+        setInt(&c.Server.BatchLimit, envBatchLimit),
+        setInt(&c.RateLimit.Burst, envRateLimitBurst),
+        setInt64(&c.RateLimit.DailyQuota, envDailyQuota),
+        setFloat(&c.RateLimit.Rate, envRateLimitRate),
+        setDuration(&c.Update.Timeout, envFetchTimeout),
+        setDuration(&c.Update.Interval, envUpdateInterval),
+    } {
+        if err != nil {
+            return err
         }
-        return fmt.Errorf("无法读取qqwry.dat: %w", err)
     }
     return nil
 }
 
-func getOrDefault(key, def string) string {
+// applyDerived 补全依赖其他字段的默认值：按分发格式选择下载地址，配置公钥但未指定签名地址时默认使用 <URL>.minisig。
+func (c *Config) applyDerived() {
+    c.Data.Path = resolvePath(c.Data.Path)
+    c.RateLimit.QuotaFile = resolvePath(c.RateLimit.QuotaFile)
+    c.Auth.KeyFile = resolvePath(c.Auth.KeyFile)
+    c.Server.WebDir = resolvePath(c.Server.WebDir)
+    c.Data.Format = strings.ToLower(strings.TrimSpace(c.Data.Format))
+    switch c.Data.Format {
+    case FormatDat:
+        if c.Data.URL == "" {
+            c.Data.URL = defaultDataURL
+        }
+    case FormatCZ88:
+        if c.Data.URL == "" {
+            c.Data.URL = defaultCZ88DataURL
+        }
+        if c.Data.CopywriteURL == "" {
+            c.Data.CopywriteURL = defaultCZ88CopywriteURL
+        }
+    }
+    if c.Data.PublicKey != "" && c.Data.SignatureURL == "" {
+        c.Data.SignatureURL = c.Data.URL + ".minisig"
+    }
+}
+
+func setString(dst *string, key string) {
     if val := os.Getenv(key); val != "" {
-        return val
+        *dst = val
     }
-    return def
+}
+
+func setBool(dst *bool, key string) {
+    if val := os.Getenv(key); val != "" {
+        *dst = isTruthy(val)
+    }
+}
+
+// setList 读取逗号分隔的列表。
+func setList(dst *[]string, key string) {
+    val := os.Getenv(key)
+    if val == "" {
+        return
+    }
+    var out []string
+    for _, item := range strings.Split(val, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            out = append(out, item)
+        }
+    }
+    *dst = out
+}
+
+func setInt(dst *int, key string) error {
+    val := os.Getenv(key)
+    if val == "" {
+        return nil
+    }
+    n, err := strconv.Atoi(strings.TrimSpace(val))
+    if err != nil {
+        return fmt.Errorf("%s 不是合法的整数: %q", key, val)
+    }
+    *dst = n
+    return nil
+}
+
+func setInt64(dst *int64, key string) error {
+    val := os.Getenv(key)
+    if val == "" {
+        return nil
+    }
+    n, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+    if err != nil {
+        return fmt.Errorf("%s 不是合法的整数: %q", key, val)
+    }
+    *dst = n
+    return nil
+}
+
+func setFloat(dst *float64, key string) error {
+    val := os.Getenv(key)
+    if val == "" {
+        return nil
+    }
+    f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+    if err != nil {
+        return fmt.Errorf("%s 不是合法的数字: %q", key, val)
+    }
+    *dst = f
+    return nil
+}
+
+// setDuration 读取 Go 时长格式（如 30s、10m、24h）的环境变量。
+func setDuration(dst *Duration, key string) error {
+    val := os.Getenv(key)
+    if val == "" {
+        return nil
+    }
+    d, err := time.ParseDuration(strings.TrimSpace(val))
+    if err != nil {
+        return fmt.Errorf("%s 不是合法的时长: %q", key, val)
+    }
+    *dst = Duration(d)
+    return nil
 }
 
 func resolvePath(p string) string {
-    if filepath.IsAbs(p) {
+    if p == "" || filepath.IsAbs(p) {
         return p
     }
     abs, err := filepath.Abs(p)
@@ -0,0 +1,97 @@
+package config
+
+import (
+    "bytes"
+    "compress/zlib"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+)
+
+// 数据文件的分发格式
+const (
+    FormatDat  = "dat"  // 标准 qqwry.dat，下载后直接使用
+    FormatCZ88 = "cz88" // 纯真官方分发包：qqwry.rar 需借助 copywrite.rar 中的密钥解密并解压
+)
+
+// cz88 官方分发包格式常量：copywrite.rar 以 "CZIP" 开头，偏移 4 为数据版本（如 20241016），
+// 偏移 20 为解密密钥；qqwry.rar 的前 0x200 字节以密钥派生的字节序列异或加密，其后为 zlib 流。
+const (
+    cz88Magic        = "CZIP"
+    cz88VersionAt    = 4
+    cz88KeyAt        = 20
+    cz88EncryptedLen = 0x200
+    maxCopywriteSize = 4 << 10
+    maxUnpackedSize  = 256 << 20
+
+    defaultCZ88DataURL      = "http://update.cz88.net/ip/qqwry.rar"
+    defaultCZ88CopywriteURL = "http://update.cz88.net/ip/copywrite.rar"
+)
+
+// copywrite 为 copywrite.rar 中解析出的版本与密钥。
+type copywrite struct {
+    version uint32
+    key     uint32
+}
+
+// parseCopywrite 解析 copywrite.rar 的文件头。
+func parseCopywrite(data []byte) (copywrite, error) {
+    if len(data) < cz88KeyAt+4 || string(data[:len(cz88Magic)]) != cz88Magic {
+        return copywrite{}, errors.New("copywrite.rar 格式不合法")
+    }
+    return copywrite{
+        version: binary.LittleEndian.Uint32(data[cz88VersionAt:]),
+        key:     binary.LittleEndian.Uint32(data[cz88KeyAt:]),
+    }, nil
+}
+
+// unpackCZ88 使用 copywrite.rar 中的密钥解密 qqwry.rar 并解压为标准 qqwry.dat 内容。
+func unpackCZ88(packed []byte, cw copywrite) ([]byte, error) {
+    buf := append([]byte(nil), packed...)
+    key := cw.key
+    for i := 0; i < cz88EncryptedLen && i < len(buf); i++ {
+        key = (key*0x805 + 1) & 0xff
+        buf[i] ^= byte(key)
+    }
+
+    zr, err := zlib.NewReader(bytes.NewReader(buf))
+    if err != nil {
+        return nil, fmt.Errorf("qqwry.rar 解密后不是合法的 zlib 数据: %w", err)
+    }
+    defer zr.Close()
+    out, err := io.ReadAll(io.LimitReader(zr, maxUnpackedSize+1))
+    if err != nil {
+        return nil, fmt.Errorf("qqwry.rar 解压失败: %w", err)
+    }
+    if len(out) > maxUnpackedSize {
+        return nil, fmt.Errorf("qqwry.rar 解压后超过 %d 字节上限", maxUnpackedSize)
+    }
+    return out, nil
+}
+
+// unpackCZ88File 获取 copywrite.rar 密钥，将下载的分发包 src 解包写入 dest。
+func unpackCZ88File(client *http.Client, copywriteURL, src, dest string) (uint32, error) {
+    raw, err := fetchSmall(client, copywriteURL, maxCopywriteSize)
+    if err != nil {
+        return 0, fmt.Errorf("获取 copywrite.rar 失败: %w", err)
+    }
+    cw, err := parseCopywrite(raw)
+    if err != nil {
+        return 0, err
+    }
+    packed, err := os.ReadFile(src)
+    if err != nil {
+        return 0, fmt.Errorf("读取 qqwry.rar 失败: %w", err)
+    }
+    data, err := unpackCZ88(packed, cw)
+    if err != nil {
+        return 0, err
+    }
+    if err := os.WriteFile(dest, data, 0o644); err != nil {
+        return 0, fmt.Errorf("写入解包后的数据文件失败: %w", err)
+    }
+    return cw.version, nil
+}
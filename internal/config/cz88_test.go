@@ -0,0 +1,156 @@
+package config
+
+import (
+    "bytes"
+    "compress/zlib"
+    "context"
+    "encoding/binary"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "ipservice/internal/ipdb"
+    "ipservice/pkg/qqwry/qqwrytest"
+)
+
+const (
+    fixtureVersion = 20241016
+    fixtureKey     = 0x1234abcd
+)
+
+// buildCopywrite 构造 copywrite.rar：CZIP 文件头、偏移 4 的版本与偏移 20 的密钥。
+func buildCopywrite(version, key uint32) []byte {
+    data := make([]byte, 0x100)
+    copy(data, cz88Magic)
+    binary.LittleEndian.PutUint32(data[cz88VersionAt:], version)
+    binary.LittleEndian.PutUint32(data[cz88KeyAt:], key)
+    return data
+}
+
+// buildCZ88Package 将 qqwry.dat 内容按官方格式打包：先 zlib 压缩，再以密钥序列异或前 0x200 字节。
+func buildCZ88Package(t *testing.T, dat []byte, key uint32) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    zw := zlib.NewWriter(&buf)
+    if _, err := zw.Write(dat); err != nil {
+        t.Fatal(err)
+    }
+    if err := zw.Close(); err != nil {
+        t.Fatal(err)
+    }
+    packed := buf.Bytes()
+    for i := 0; i < cz88EncryptedLen && i < len(packed); i++ {
+        key = (key*0x805 + 1) & 0xff
+        packed[i] ^= byte(key)
+    }
+    return packed
+}
+
+// newCZ88Server 启动模拟的官方更新服务器，提供 /qqwry.rar 与 /copywrite.rar。
+func newCZ88Server(t *testing.T, dat []byte, key uint32) *httptest.Server {
+    t.Helper()
+    packed := buildCZ88Package(t, dat, key)
+    copywrite := buildCopywrite(fixtureVersion, key)
+    mux := http.NewServeMux()
+    mux.HandleFunc("/qqwry.rar", func(w http.ResponseWriter, _ *http.Request) {
+        w.Write(packed)
+    })
+    mux.HandleFunc("/copywrite.rar", func(w http.ResponseWriter, _ *http.Request) {
+        w.Write(copywrite)
+    })
+    srv := httptest.NewServer(mux)
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+func TestUnpackCZ88File(t *testing.T) {
+    dat := qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据"))
+    srv := newCZ88Server(t, dat, fixtureKey)
+
+    dir := t.TempDir()
+    src := filepath.Join(dir, "qqwry.rar")
+    dest := filepath.Join(dir, "qqwry.dat")
+    if err := os.WriteFile(src, buildCZ88Package(t, dat, fixtureKey), 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    version, err := unpackCZ88File(srv.Client(), srv.URL+"/copywrite.rar", src, dest)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if version != fixtureVersion {
+        t.Fatalf("version = %d，期望 %d", version, fixtureVersion)
+    }
+    got, err := os.ReadFile(dest)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(got, dat) {
+        t.Fatal("解包结果与原始数据不一致")
+    }
+    if err := ipdb.ValidateFile(dest); err != nil {
+        t.Fatalf("解包结果未通过结构校验: %v", err)
+    }
+}
+
+func TestUnpackCZ88RejectsWrongKey(t *testing.T) {
+    dat := qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据"))
+    if _, err := unpackCZ88(buildCZ88Package(t, dat, fixtureKey), copywrite{key: fixtureKey + 1}); err == nil {
+        t.Fatal("密钥错误时应返回错误")
+    }
+    if _, err := parseCopywrite([]byte("RIFF0000000000000000000000")); err == nil {
+        t.Fatal("缺少 CZIP 文件头时应返回错误")
+    }
+}
+
+func TestDownloaderCZ88(t *testing.T) {
+    dat := qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据"))
+    srv := newCZ88Server(t, dat, fixtureKey)
+
+    path := filepath.Join(t.TempDir(), "data", "qqwry.dat")
+    d := newDownloader(Source{
+        URL:          srv.URL + "/qqwry.rar",
+        Format:       FormatCZ88,
+        CopywriteURL: srv.URL + "/copywrite.rar",
+    })
+    changed, err := d.fetch(context.Background(), path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !changed {
+        t.Fatal("首次下载应替换本地文件")
+    }
+    if err := ipdb.ValidateFile(path); err != nil {
+        t.Fatalf("下载结果未通过结构校验: %v", err)
+    }
+    for _, leftover := range []string{path + ".part", path + ".unpacked"} {
+        if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+            t.Fatalf("临时文件 %s 未清理", leftover)
+        }
+    }
+}
+
+func TestDownloaderCZ88KeepsFileOnBadKey(t *testing.T) {
+    packed := buildCZ88Package(t, newDat, fixtureKey)
+    mux := http.NewServeMux()
+    mux.HandleFunc("/qqwry.rar", func(w http.ResponseWriter, _ *http.Request) { w.Write(packed) })
+    mux.HandleFunc("/copywrite.rar", func(w http.ResponseWriter, _ *http.Request) {
+        w.Write(buildCopywrite(fixtureVersion, fixtureKey+1))
+    })
+    srv := httptest.NewServer(mux)
+    defer srv.Close()
+
+    // 已有的旧版本数据文件在解包失败后应保持原样
+    path := existingData(t)
+    d := newDownloader(Source{URL: srv.URL + "/qqwry.rar", Format: FormatCZ88, CopywriteURL: srv.URL + "/copywrite.rar"})
+    changed, err := d.fetch(context.Background(), path)
+    if err == nil || changed {
+        t.Fatalf("changed = %v，err = %v，密钥错误时下载应失败", changed, err)
+    }
+    assertData(t, path, oldDat)
+    if _, err := os.Stat(path + ".unpacked"); !os.IsNotExist(err) {
+        t.Fatal("临时文件 .unpacked 未清理")
+    }
+}
@@ -0,0 +1,328 @@
+package config
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    initialBackoff   = 2 * time.Second
+    maxBackoff       = time.Minute
+    progressInterval = 5 * time.Second
+)
+
+// downloadMeta 保存在 <path>.meta 中：记录上次成功下载的缓存校验信息（用于条件请求），
+// 以及未完成下载的来源与校验值（用于 Range 断点续传）。
+type downloadMeta struct {
+    URL          string `json:"url,omitempty"`
+    ETag         string `json:"etag,omitempty"`
+    LastModified string `json:"last_modified,omitempty"`
+
+    PartialURL       string `json:"partial_url,omitempty"`
+    PartialValidator string `json:"partial_validator,omitempty"`
+}
+
+// downloader 负责按镜像顺序、指数退避地下载数据文件，支持代理环境变量、断点续传与条件请求。
+type downloader struct {
+    src    Source
+    client *http.Client
+}
+
+func newDownloader(src Source) *downloader {
+    transport := &http.Transport{
+        Proxy: http.ProxyFromEnvironment,
+        DialContext: (&net.Dialer{
+            Timeout:   30 * time.Second,
+            KeepAlive: 30 * time.Second,
+        }).DialContext,
+        ForceAttemptHTTP2:     true,
+        TLSHandshakeTimeout:   15 * time.Second,
+        ResponseHeaderTimeout: 30 * time.Second,
+        IdleConnTimeout:       90 * time.Second,
+    }
+    // 整体超时由每次尝试的 context 控制，避免大文件在慢速网络下被客户端超时截断
+    return &downloader{src: src, client: &http.Client{Transport: transport}}
+}
+
+// fetch 依次尝试全部下载地址，整轮失败后指数退避重试；返回值表示本地文件是否被替换。
+func (d *downloader) fetch(ctx context.Context, path string) (bool, error) {
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return false, fmt.Errorf("创建数据目录失败: %w", err)
+    }
+
+    meta := loadMeta(path)
+    _, statErr := os.Stat(path)
+    exists := statErr == nil
+
+    backoff := initialBackoff
+    var lastErr error
+    for round := 0; round <= d.src.Retries; round++ {
+        if round > 0 {
+            log.Printf("全部下载地址均失败，%s 后进行第 %d 次重试", backoff, round)
+            select {
+            case <-ctx.Done():
+                return false, ctx.Err()
+            case <-time.After(backoff):
+            }
+            backoff = min(backoff*2, maxBackoff)
+        }
+        for _, url := range d.src.urls() {
+            changed, err := d.attempt(ctx, path, url, meta, exists)
+            if err == nil {
+                return changed, nil
+            }
+            if ctx.Err() != nil {
+                return false, ctx.Err()
+            }
+            lastErr = err
+            log.Printf("从 %s 下载qqwry.dat失败: %v", url, err)
+        }
+    }
+    return false, fmt.Errorf("下载qqwry.dat失败: %w", lastErr)
+}
+
+// attempt 从单个地址下载一次，成功并通过校验后替换目标文件。
+func (d *downloader) attempt(ctx context.Context, path, url string, meta *downloadMeta, exists bool) (bool, error) {
+    if d.src.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, d.src.Timeout)
+        defer cancel()
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return false, fmt.Errorf("构造下载请求失败: %w", err)
+    }
+    req.Header.Set("User-Agent", userAgent)
+
+    // 本地文件来自同一地址时发送条件请求，远端未变化则直接跳过
+    if exists && meta.URL == url {
+        if meta.ETag != "" {
+            req.Header.Set("If-None-Match", meta.ETag)
+        }
+        if meta.LastModified != "" {
+            req.Header.Set("If-Modified-Since", meta.LastModified)
+        }
+    }
+
+    // 存在同一地址的未完成下载时尝试续传；If-Range 确保远端文件变化时回退为完整下载
+    partPath := path + ".part"
+    var offset int64
+    if fi, err := os.Stat(partPath); err == nil && fi.Size() > 0 && meta.PartialURL == url {
+        offset = fi.Size()
+        req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+        if meta.PartialValidator != "" {
+            req.Header.Set("If-Range", meta.PartialValidator)
+        }
+    }
+
+    resp, err := d.client.Do(req)
+    if err != nil {
+        return false, err
+    }
+    defer resp.Body.Close()
+
+    switch resp.StatusCode {
+    case http.StatusNotModified:
+        log.Printf("远端数据未变化，跳过下载: %s", url)
+        return false, nil
+    case http.StatusPartialContent:
+        if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+            return false, fmt.Errorf("续传响应的 Content-Range 不匹配: %q", resp.Header.Get("Content-Range"))
+        }
+        log.Printf("从 %d 字节处续传: %s", offset, url)
+    case http.StatusOK:
+        offset = 0
+    case http.StatusRequestedRangeNotSatisfiable:
+        os.Remove(partPath)
+        meta.clearPartial()
+        saveMeta(path, meta)
+        return false, errors.New("断点已失效，已清除未完成的下载")
+    default:
+        return false, fmt.Errorf("HTTP %d", resp.StatusCode)
+    }
+
+    meta.PartialURL = url
+    meta.PartialValidator = rangeValidator(resp.Header)
+    saveMeta(path, meta)
+
+    flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+    if offset == 0 {
+        flags |= os.O_TRUNC
+    }
+    part, err := os.OpenFile(partPath, flags, 0o644)
+    if err != nil {
+        return false, fmt.Errorf("创建临时文件失败: %w", err)
+    }
+    total := int64(-1)
+    if resp.ContentLength >= 0 {
+        total = offset + resp.ContentLength
+    }
+    progress := &progressWriter{url: url, written: offset, total: total, last: time.Now()}
+    _, copyErr := io.Copy(io.MultiWriter(part, progress), resp.Body)
+    closeErr := part.Close()
+    if copyErr != nil {
+        return false, fmt.Errorf("写入临时文件失败（已保留断点）: %w", copyErr)
+    }
+    if closeErr != nil {
+        return false, fmt.Errorf("关闭临时文件失败: %w", closeErr)
+    }
+    progress.done()
+
+    // 官方分发包需先解包为标准 qqwry.dat，后续校验针对解包结果进行
+    target, names := partPath, checksumNames(url, path)
+    if d.src.Format == FormatCZ88 {
+        // 校验针对解包结果，校验和文件中仅按本地数据文件名匹配
+        target, names = path+".unpacked", []string{filepath.Base(path)}
+        defer os.Remove(target)
+        version, err := unpackCZ88File(d.client, d.src.CopywriteURL, partPath, target)
+        if err != nil {
+            os.Remove(partPath)
+            meta.clearPartial()
+            saveMeta(path, meta)
+            return false, fmt.Errorf("解包 cz88 分发包失败，已丢弃下载内容: %w", err)
+        }
+        log.Printf("已解包 cz88 分发包，数据版本 %d", version)
+    }
+
+    if err := d.verify(target, names); err != nil {
+        // 未通过校验的内容不可续传，直接丢弃
+        os.Remove(partPath)
+        meta.clearPartial()
+        saveMeta(path, meta)
+        return false, fmt.Errorf("qqwry.dat 校验未通过，已丢弃下载内容: %w", err)
+    }
+    if err := os.Rename(target, path); err != nil {
+        return false, fmt.Errorf("移动数据文件失败: %w", err)
+    }
+    os.Remove(partPath)
+
+    meta.clearPartial()
+    meta.URL = url
+    meta.ETag = resp.Header.Get("ETag")
+    meta.LastModified = resp.Header.Get("Last-Modified")
+    saveMeta(path, meta)
+    log.Printf("数据文件已更新: %s", path)
+    return true, nil
+}
+
+// verify 计算完整文件的摘要后执行摘要、签名与结构校验，names 用于在校验和文件中定位对应条目。
+func (d *downloader) verify(path string, names []string) error {
+    f, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    hash := sha256.New()
+    _, err = io.Copy(hash, f)
+    f.Close()
+    if err != nil {
+        return fmt.Errorf("计算摘要失败: %w", err)
+    }
+
+    v := &verifier{
+        sha256:       d.src.SHA256,
+        sha256URL:    d.src.SHA256URL,
+        publicKey:    d.src.PublicKey,
+        signatureURL: d.src.SignatureURL,
+        client:       d.client,
+        names:        names,
+    }
+    return v.verify(path, hash.Sum(nil))
+}
+
+// rangeValidator 选择可用于 If-Range 的校验值：强 ETag 优先，其次 Last-Modified。
+func rangeValidator(h http.Header) string {
+    if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+        return etag
+    }
+    return h.Get("Last-Modified")
+}
+
+// contentRangeStart 解析 "bytes <start>-<end>/<size>" 中的起始偏移。
+func contentRangeStart(v string) (int64, bool) {
+    v = strings.TrimSpace(v)
+    if !strings.HasPrefix(v, "bytes ") {
+        return 0, false
+    }
+    rng, _, _ := strings.Cut(strings.TrimPrefix(v, "bytes "), "/")
+    startStr, _, ok := strings.Cut(rng, "-")
+    if !ok {
+        return 0, false
+    }
+    start, err := strconv.ParseInt(startStr, 10, 64)
+    if err != nil {
+        return 0, false
+    }
+    return start, true
+}
+
+func (m *downloadMeta) clearPartial() {
+    m.PartialURL = ""
+    m.PartialValidator = ""
+}
+
+func loadMeta(path string) *downloadMeta {
+    meta := &downloadMeta{}
+    data, err := os.ReadFile(path + ".meta")
+    if err != nil {
+        return meta
+    }
+    if err := json.Unmarshal(data, meta); err != nil {
+        log.Printf("下载元数据解析失败，将忽略: %v", err)
+        return &downloadMeta{}
+    }
+    return meta
+}
+
+func saveMeta(path string, meta *downloadMeta) {
+    data, err := json.MarshalIndent(meta, "", "  ")
+    if err != nil {
+        return
+    }
+    if err := os.WriteFile(path+".meta", data, 0o644); err != nil {
+        log.Printf("保存下载元数据失败: %v", err)
+    }
+}
+
+// progressWriter 定期在日志中输出下载进度。
+type progressWriter struct {
+    url     string
+    written int64
+    total   int64
+    last    time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+    p.written += int64(len(b))
+    if time.Since(p.last) >= progressInterval {
+        p.last = time.Now()
+        p.report("下载中")
+    }
+    return len(b), nil
+}
+
+func (p *progressWriter) done() {
+    p.report("下载完成")
+}
+
+func (p *progressWriter) report(stage string) {
+    const mib = 1 << 20
+    if p.total > 0 {
+        log.Printf("%s %.1f%% (%.1f/%.1f MiB): %s", stage, float64(p.written)*100/float64(p.total),
+            float64(p.written)/mib, float64(p.total)/mib, p.url)
+        return
+    }
+    log.Printf("%s %.1f MiB: %s", stage, float64(p.written)/mib, p.url)
+}
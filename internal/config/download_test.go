@@ -0,0 +1,166 @@
+package config
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestFetchMirrorFallback(t *testing.T) {
+    var primaryHits atomic.Int32
+    primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+        primaryHits.Add(1)
+        http.Error(w, "unavailable", http.StatusInternalServerError)
+    }))
+    defer primary.Close()
+    mirror := serveFiles(t, map[string][]byte{"/qqwry.dat": newDat})
+
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    d := newDownloader(Source{URL: primary.URL + "/qqwry.dat", Mirrors: []string{mirror.URL + "/qqwry.dat"}})
+    changed, err := d.fetch(context.Background(), path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !changed || primaryHits.Load() != 1 {
+        t.Fatalf("changed = %v，主地址请求 %d 次，期望替换文件且主地址仅请求 1 次", changed, primaryHits.Load())
+    }
+    assertData(t, path, newDat)
+    if meta := loadMeta(path); meta.URL != mirror.URL+"/qqwry.dat" {
+        t.Fatalf("meta.URL = %q，期望记录实际使用的镜像地址", meta.URL)
+    }
+}
+
+// TestFetchBackoffCanceled 确认全部地址失败后在退避等待期间响应取消，不会继续发起下一轮请求。
+func TestFetchBackoffCanceled(t *testing.T) {
+    var hits atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+        hits.Add(1)
+        http.Error(w, "unavailable", http.StatusServiceUnavailable)
+    }))
+    defer srv.Close()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+    defer cancel()
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    d := newDownloader(Source{URL: srv.URL + "/a.dat", Mirrors: []string{srv.URL + "/b.dat"}, Retries: 3})
+    start := time.Now()
+    if _, err := d.fetch(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("err = %v，期望 context.DeadlineExceeded", err)
+    }
+    if elapsed := time.Since(start); elapsed >= initialBackoff {
+        t.Fatalf("取消后仍等待了 %v", elapsed)
+    }
+    if n := hits.Load(); n != 2 {
+        t.Fatalf("请求 %d 次，期望首轮每个地址各 1 次", n)
+    }
+}
+
+// writePartial 模拟上次中断的下载：写入临时文件与对应的续传元数据。
+func writePartial(t *testing.T, path string, data []byte, url, validator string) {
+    t.Helper()
+    if err := os.WriteFile(path+".part", data, 0o644); err != nil {
+        t.Fatal(err)
+    }
+    saveMeta(path, &downloadMeta{PartialURL: url, PartialValidator: validator})
+}
+
+func TestFetchResumesPartial(t *testing.T) {
+    const etag = `"v1"`
+    offset := len(newDat) / 2
+    var gotRange, gotIfRange string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotRange, gotIfRange = r.Header.Get("Range"), r.Header.Get("If-Range")
+        w.Header().Set("ETag", etag)
+        w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(newDat)-1, len(newDat)))
+        w.WriteHeader(http.StatusPartialContent)
+        w.Write(newDat[offset:])
+    }))
+    defer srv.Close()
+
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    url := srv.URL + "/qqwry.dat"
+    writePartial(t, path, newDat[:offset], url, etag)
+
+    changed, err := newDownloader(Source{URL: url}).fetch(context.Background(), path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := fmt.Sprintf("bytes=%d-", offset); gotRange != want || gotIfRange != etag {
+        t.Fatalf("Range = %q，If-Range = %q，期望 %q 与 %q", gotRange, gotIfRange, want, etag)
+    }
+    if !changed {
+        t.Fatal("续传完成后应替换本地文件")
+    }
+    assertData(t, path, newDat)
+    if meta := loadMeta(path); meta.PartialURL != "" || meta.ETag != etag {
+        t.Fatalf("meta = %+v，期望清除续传信息并记录 ETag", meta)
+    }
+}
+
+// TestFetchRangeIgnored 确认服务端忽略 Range 返回完整内容时从头写入，而不是追加到旧的临时文件之后。
+func TestFetchRangeIgnored(t *testing.T) {
+    var gotRange string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotRange = r.Header.Get("Range")
+        w.Write(newDat)
+    }))
+    defer srv.Close()
+
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    url := srv.URL + "/qqwry.dat"
+    writePartial(t, path, oldDat[:len(oldDat)/2], url, `"stale"`)
+
+    if _, err := newDownloader(Source{URL: url}).fetch(context.Background(), path); err != nil {
+        t.Fatal(err)
+    }
+    if gotRange == "" {
+        t.Fatal("存在未完成的下载时应发送 Range 请求")
+    }
+    assertData(t, path, newDat)
+}
+
+func TestFetchNotModified(t *testing.T) {
+    const (
+        etag         = `"v1"`
+        lastModified = "Wed, 16 Oct 2024 00:00:00 GMT"
+    )
+    var requests, notModified atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+            notModified.Add(1)
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+        w.Header().Set("ETag", etag)
+        w.Header().Set("Last-Modified", lastModified)
+        w.Write(newDat)
+    }))
+    defer srv.Close()
+
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    d := newDownloader(Source{URL: srv.URL + "/qqwry.dat"})
+    if changed, err := d.fetch(context.Background(), path); err != nil || !changed {
+        t.Fatalf("首次下载 changed = %v，err = %v", changed, err)
+    }
+
+    // 本地文件被替换为其他内容后，304 响应不应改动它
+    if err := os.WriteFile(path, oldDat, 0o644); err != nil {
+        t.Fatal(err)
+    }
+    changed, err := d.fetch(context.Background(), path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if changed || notModified.Load() != 1 || requests.Load() != 2 {
+        t.Fatalf("changed = %v，304 响应 %d 次，期望未替换且命中条件请求", changed, notModified.Load())
+    }
+    assertData(t, path, oldDat)
+}
@@ -1,17 +1,46 @@
 package config
 
 import (
+    "context"
     "errors"
     "fmt"
-    "io"
-    "net/http"
     "os"
-    "path/filepath"
     "time"
 )
 
+const userAgent = "ipservice/1.0 (+https://github.com)"
+
+// Source 描述数据文件的下载来源、重试策略与可选的完整性校验要求。
+type Source struct {
+    URL     string
+    Mirrors []string // 备用镜像，主地址失败后按顺序尝试
+
+    Format       string // 分发格式：FormatDat 或 FormatCZ88
+    CopywriteURL string // FormatCZ88 时获取解密密钥的 copywrite.rar 地址
+
+    Retries int           // 全部地址均失败后的重试轮数
+    Timeout time.Duration // 单次下载的超时时间
+
+    SHA256       string // 固定的 SHA-256 摘要（十六进制）
+    SHA256URL    string // 校验和文件地址，兼容 sha256sum 输出格式
+    PublicKey    string // minisign 公钥或 base64 编码的 ed25519 公钥
+    SignatureURL string // 分离签名地址
+}
+
+// urls 返回按尝试顺序排列的下载地址。
+func (s Source) urls() []string {
+    var out []string
+    for _, u := range append([]string{s.URL}, s.Mirrors...) {
+        if u != "" {
+            out = append(out, u)
+        }
+    }
+    return out
+}
+
 // ensureQQWryFile 确保本地存在 qqwry.dat；若不存在且提供了 URL，则尝试下载。
-func ensureQQWryFile(path, url string) error {
+// 下载内容需依次通过摘要、签名与结构校验后才会替换到目标路径，任一环节失败都不会覆盖已有文件。
+func ensureQQWryFile(path string, src Source) error {
     if path == "" {
         return errors.New("qqwry.dat 路径不能为空")
     }
@@ -21,49 +50,19 @@ func ensureQQWryFile(path, url string) error {
         return fmt.Errorf("检查qqwry.dat失败: %w", err)
     }
 
-    if url == "" {
+    if len(src.urls()) == 0 {
         return fmt.Errorf("缺少下载地址，请设置 %s 或手动放置数据文件", envQQwryURL)
     }
+    _, err := newDownloader(src).fetch(context.Background(), path)
+    return err
+}
 
-    dir := filepath.Dir(path)
-    if err := os.MkdirAll(dir, 0o755); err != nil {
-        return fmt.Errorf("创建数据目录失败: %w", err)
-    }
-
-    req, err := http.NewRequest(http.MethodGet, url, nil)
-    if err != nil {
-        return fmt.Errorf("构造下载请求失败: %w", err)
-    }
-    req.Header.Set("User-Agent", "ipservice/1.0 (+https://github.com)")
-
-    client := &http.Client{Timeout: 60 * time.Second}
-    resp, err := client.Do(req)
-    if err != nil {
-        return fmt.Errorf("下载qqwry.dat失败: %w", err)
-    }
-    defer resp.Body.Close()
-    if resp.StatusCode != http.StatusOK {
-        return fmt.Errorf("下载qqwry.dat失败: HTTP %d", resp.StatusCode)
-    }
-
-    tmp, err := os.CreateTemp(dir, "qqwry-*.dat.tmp")
-    if err != nil {
-        return fmt.Errorf("创建临时文件失败: %w", err)
-    }
-    defer func() {
-        tmp.Close()
-        os.Remove(tmp.Name())
-    }()
-
-    if _, err := io.Copy(tmp, resp.Body); err != nil {
-        return fmt.Errorf("写入临时文件失败: %w", err)
-    }
-    if err := tmp.Close(); err != nil {
-        return fmt.Errorf("关闭临时文件失败: %w", err)
-    }
-
-    if err := os.Rename(tmp.Name(), path); err != nil {
-        return fmt.Errorf("移动数据文件失败: %w", err)
+// UpdateData 通过条件请求检查远端数据是否更新，有变化时下载、校验并替换本地文件。
+// 返回值表示本地文件是否被替换，调用方可据此触发热加载。
+func (c *Config) UpdateData(ctx context.Context) (bool, error) {
+    src := c.Source()
+    if len(src.urls()) == 0 {
+        return false, fmt.Errorf("缺少下载地址，请设置 %s", envQQwryURL)
     }
-    return nil
+    return newDownloader(src).fetch(ctx, c.Data.Path)
 }
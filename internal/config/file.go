@@ -0,0 +1,78 @@
+package config
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/pelletier/go-toml/v2"
+    "gopkg.in/yaml.v3"
+)
+
+// Duration 为可在配置文件中以 "15s"、"10m" 等字符串书写的时长。
+type Duration time.Duration
+
+// MarshalText 实现 encoding.TextMarshaler，YAML、TOML 与 JSON 均输出为时长字符串。
+func (d Duration) MarshalText() ([]byte, error) {
+    return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler。
+func (d *Duration) UnmarshalText(text []byte) error {
+    v, err := time.ParseDuration(strings.TrimSpace(string(text)))
+    if err != nil {
+        return fmt.Errorf("不是合法的时长: %q", text)
+    }
+    *d = Duration(v)
+    return nil
+}
+
+// decodeFile 按扩展名解析 YAML（.yaml/.yml）或 TOML（.toml）配置文件，未知字段视为错误。
+func decodeFile(path string, cfg *Config) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("读取配置文件失败: %w", err)
+    }
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        dec := yaml.NewDecoder(bytes.NewReader(data))
+        dec.KnownFields(true)
+        if err := dec.Decode(cfg); err != nil && err != io.EOF {
+            return fmt.Errorf("解析 YAML 配置文件 %s 失败: %w", path, err)
+        }
+    case ".toml":
+        dec := toml.NewDecoder(bytes.NewReader(data))
+        dec.DisallowUnknownFields()
+        if err := dec.Decode(cfg); err != nil {
+            return fmt.Errorf("解析 TOML 配置文件 %s 失败: %w", path, err)
+        }
+    default:
+        return fmt.Errorf("不支持的配置文件格式: %s（仅支持 .yaml、.yml、.toml）", path)
+    }
+    return nil
+}
+
+// Encode 以 yaml、toml 或 json 格式输出配置，用于展示合并后的生效配置。
+func (c *Config) Encode(w io.Writer, format string) error {
+    switch strings.ToLower(format) {
+    case "", "yaml", "yml":
+        enc := yaml.NewEncoder(w)
+        enc.SetIndent(2)
+        defer enc.Close()
+        return enc.Encode(c)
+    case "toml":
+        return toml.NewEncoder(w).Encode(c)
+    case "json":
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        enc.SetEscapeHTML(false)
+        return enc.Encode(c)
+    default:
+        return fmt.Errorf("不支持的输出格式: %s", format)
+    }
+}
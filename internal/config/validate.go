@@ -0,0 +1,170 @@
+package config
+
+import (
+    "encoding/hex"
+    "fmt"
+    "net/netip"
+    "net/url"
+    "strings"
+)
+
+// FieldError 描述某个配置项的问题，Field 为配置文件中的字段路径，如 server.batch_limit。
+type FieldError struct {
+    Field   string
+    Message string
+}
+
+func (e *FieldError) Error() string {
+    return e.Field + ": " + e.Message
+}
+
+// ValidationError 汇总配置校验发现的全部问题。
+type ValidationError struct {
+    Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+    msgs := make([]string, len(e.Errors))
+    for i, fe := range e.Errors {
+        msgs[i] = fe.Error()
+    }
+    return fmt.Sprintf("配置校验失败（%d 项）: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Validate 校验全部配置项，一次性返回所有问题而非遇到首个错误即停止。
+func (c *Config) Validate() error {
+    v := &ValidationError{}
+    add := func(field, format string, args ...any) {
+        v.Errors = append(v.Errors, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+    }
+
+    if c.Server.Listen == "" {
+        add("server.listen", "监听地址不能为空")
+    }
+    for _, d := range []struct {
+        field string
+        value Duration
+    }{
+        {"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+        {"server.read_timeout", c.Server.ReadTimeout},
+        {"server.write_timeout", c.Server.WriteTimeout},
+        {"server.idle_timeout", c.Server.IdleTimeout},
+        {"server.shutdown_timeout", c.Server.ShutdownTimeout},
+    } {
+        if d.value < 0 {
+            add(d.field, "不能为负数")
+        }
+    }
+    for i, p := range c.Server.TrustedProxies {
+        if _, err := netip.ParsePrefix(p); err != nil {
+            if _, err := netip.ParseAddr(p); err != nil {
+                add(fmt.Sprintf("server.trusted_proxies[%d]", i), "不是合法的 IP 或网段: %q", p)
+            }
+        }
+    }
+    if c.Server.BatchLimit <= 0 {
+        add("server.batch_limit", "必须为正整数")
+    }
+    if c.Server.CIDRLimit <= 0 {
+        add("server.cidr_limit", "必须为正整数")
+    }
+    for i, name := range c.Server.Compat {
+        switch name {
+        case "ip-api", "ipinfo", "taobao", "pconline":
+        default:
+            add(fmt.Sprintf("server.compat[%d]", i), "仅支持 ip-api、ipinfo、taobao、pconline，当前为 %q", name)
+        }
+    }
+
+    if c.Data.Path == "" {
+        add("data.path", "qqwry.dat 路径不能为空")
+    }
+    switch c.Data.Format {
+    case FormatDat:
+    case FormatCZ88:
+        checkURL(add, "data.copywrite_url", c.Data.CopywriteURL)
+    default:
+        add("data.format", "仅支持 %s 或 %s", FormatDat, FormatCZ88)
+    }
+    checkURL(add, "data.url", c.Data.URL)
+    for i, m := range c.Data.Mirrors {
+        checkURL(add, fmt.Sprintf("data.mirrors[%d]", i), m)
+    }
+    if c.Data.SHA256 != "" {
+        if b, err := hex.DecodeString(strings.TrimSpace(c.Data.SHA256)); err != nil || len(b) != 32 {
+            add("data.sha256", "必须为 64 位十六进制 SHA-256 摘要")
+        }
+    }
+    if c.Data.SHA256URL != "" {
+        checkURL(add, "data.sha256_url", c.Data.SHA256URL)
+    }
+    if c.Data.PublicKey != "" {
+        if _, _, err := parsePublicKey(c.Data.PublicKey); err != nil {
+            add("data.public_key", "%v", err)
+        }
+        checkURL(add, "data.signature_url", c.Data.SignatureURL)
+    }
+
+    if c.Update.Interval < 0 {
+        add("update.interval", "不能为负数")
+    }
+    if c.Update.Retries < 0 {
+        add("update.retries", "不能为负数")
+    }
+    if c.Update.Timeout < 0 {
+        add("update.timeout", "不能为负数")
+    }
+
+    if c.RateLimit.Enabled {
+        if c.RateLimit.Rate <= 0 {
+            add("rate_limit.rate", "必须为正数")
+        }
+        if c.RateLimit.Burst <= 0 {
+            add("rate_limit.burst", "必须为正整数")
+        }
+    }
+    if c.RateLimit.DailyQuota < 0 {
+        add("rate_limit.daily_quota", "不能为负数")
+    }
+
+    if c.Auth.Enabled && c.Auth.KeyFile == "" {
+        add("auth.key_file", "启用认证时不能为空")
+    }
+
+    if c.CORS.Enabled {
+        if len(c.CORS.AllowOrigins) == 0 {
+            add("cors.allow_origins", "启用跨域时至少需要一个来源")
+        }
+        for i, origin := range c.CORS.AllowOrigins {
+            if origin == "*" {
+                if c.CORS.AllowCredentials {
+                    add(fmt.Sprintf("cors.allow_origins[%d]", i), "允许携带凭据时不能使用 \"*\"，请列出具体来源")
+                }
+                continue
+            }
+            u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+            if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+                add(fmt.Sprintf("cors.allow_origins[%d]", i), "必须为 \"*\" 或 scheme://host[:port] 形式: %q", origin)
+            }
+        }
+        if c.CORS.MaxAge < 0 {
+            add("cors.max_age", "不能为负数")
+        }
+    }
+
+    if len(v.Errors) == 0 {
+        return nil
+    }
+    return v
+}
+
+func checkURL(add func(field, format string, args ...any), field, raw string) {
+    if raw == "" {
+        add(field, "不能为空")
+        return
+    }
+    u, err := url.Parse(raw)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        add(field, "不是合法的 http(s) 地址: %q", raw)
+    }
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/ed25519"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+
+    "golang.org/x/crypto/blake2b"
+
+    "ipservice/internal/ipdb"
+)
+
+// 校验失败时返回的错误，调用方据此判定下载内容不可信
+var (
+    ErrChecksumMismatch = errors.New("checksum mismatch")
+    ErrBadSignature     = errors.New("signature verification failed")
+)
+
+// minisign 密钥与签名格式常量，参见 https://jedisct1.github.io/minisign/
+const (
+    minisignAlgEd       = "Ed" // 对原始文件签名
+    minisignAlgHashedEd = "ED" // 对文件的 BLAKE2b-512 摘要签名
+    minisignKeyIDLen    = 8
+    maxSignatureSize    = 4 << 10
+    maxChecksumSize     = 64 << 10
+)
+
+// verifier 在下载完成、替换正式文件前依次执行摘要比对、签名校验与结构解析。
+type verifier struct {
+    sha256       string
+    sha256URL    string
+    publicKey    string
+    signatureURL string
+    client       *http.Client
+
+    // names 为在多条目校验和文件中定位本文件所用的文件名，如下载地址与本地路径的文件名
+    names []string
+}
+
+// verify 校验临时文件，digest 为下载过程中同步计算的 SHA-256。
+func (v *verifier) verify(path string, digest []byte) error {
+    if err := v.verifyChecksum(digest); err != nil {
+        return err
+    }
+    if err := v.verifySignature(path); err != nil {
+        return err
+    }
+    if err := ipdb.ValidateFile(path); err != nil {
+        return fmt.Errorf("下载的数据文件结构不合法: %w", err)
+    }
+    return nil
+}
+
+func (v *verifier) verifyChecksum(digest []byte) error {
+    got := hex.EncodeToString(digest)
+    if v.sha256 != "" && !strings.EqualFold(strings.TrimSpace(v.sha256), got) {
+        return fmt.Errorf("%w: 期望 %s，实际 %s", ErrChecksumMismatch, v.sha256, got)
+    }
+    if v.sha256URL == "" {
+        return nil
+    }
+    body, err := fetchSmall(v.client, v.sha256URL, maxChecksumSize)
+    if err != nil {
+        return fmt.Errorf("获取校验和文件失败: %w", err)
+    }
+    want, err := parseChecksumFile(body, v.names...)
+    if err != nil {
+        return err
+    }
+    if !strings.EqualFold(want, got) {
+        return fmt.Errorf("%w: 校验和文件期望 %s，实际 %s", ErrChecksumMismatch, want, got)
+    }
+    return nil
+}
+
+// parseChecksumFile 兼容 sha256sum 输出（"<hex>  qqwry.dat"，二进制模式为 "<hex> *qqwry.dat"）与仅包含摘要的文件。
+// 文件名与 names 之一相同的条目优先；没有匹配时仅在文件只有一条摘要时采用该摘要，避免误用其他文件的校验和。
+func parseChecksumFile(body []byte, names ...string) (string, error) {
+    var sums []string
+    scanner := bufio.NewScanner(bytes.NewReader(body))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+            continue
+        }
+        sum := fields[0]
+        if len(sum) != sha256.Size*2 {
+            continue
+        }
+        if _, err := hex.DecodeString(sum); err != nil {
+            continue
+        }
+        if len(fields) > 1 {
+            name := path.Base(strings.TrimPrefix(fields[1], "*"))
+            for _, want := range names {
+                if name == want {
+                    return sum, nil
+                }
+            }
+        }
+        sums = append(sums, sum)
+    }
+    switch len(sums) {
+    case 0:
+        return "", fmt.Errorf("%w: 校验和文件中未找到合法的 SHA-256 摘要", ErrChecksumMismatch)
+    case 1:
+        return sums[0], nil
+    default:
+        return "", fmt.Errorf("%w: 校验和文件包含多条摘要，但没有与 %s 对应的条目", ErrChecksumMismatch, strings.Join(names, "、"))
+    }
+}
+
+// checksumNames 返回在校验和文件中代表下载内容的文件名：下载地址路径的文件名与本地数据文件名。
+func checksumNames(rawURL, localPath string) []string {
+    var names []string
+    if u, err := url.Parse(rawURL); err == nil && u.Path != "" && !strings.HasSuffix(u.Path, "/") {
+        names = append(names, path.Base(u.Path))
+    }
+    return append(names, filepath.Base(localPath))
+}
+
+func (v *verifier) verifySignature(path string) error {
+    if v.publicKey == "" {
+        return nil
+    }
+    pub, keyID, err := parsePublicKey(v.publicKey)
+    if err != nil {
+        return err
+    }
+    if v.signatureURL == "" {
+        return fmt.Errorf("%w: 已配置公钥但缺少签名地址", ErrBadSignature)
+    }
+    sig, err := fetchSmall(v.client, v.signatureURL, maxSignatureSize)
+    if err != nil {
+        return fmt.Errorf("获取签名文件失败: %w", err)
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("读取待校验文件失败: %w", err)
+    }
+
+    if bytes.HasPrefix(sig, []byte("untrusted comment:")) {
+        return verifyMinisign(pub, keyID, data, sig)
+    }
+    return verifyEd25519(pub, data, sig)
+}
+
+// parsePublicKey 支持 minisign 公钥（可含 "untrusted comment" 首行）与 base64 编码的 32 字节 ed25519 公钥。
+func parsePublicKey(value string) (ed25519.PublicKey, []byte, error) {
+    lines := strings.Split(strings.TrimSpace(value), "\n")
+    encoded := strings.TrimSpace(lines[len(lines)-1])
+    raw, err := base64.StdEncoding.DecodeString(encoded)
+    if err != nil {
+        return nil, nil, fmt.Errorf("公钥不是合法的 base64: %w", err)
+    }
+    switch len(raw) {
+    case ed25519.PublicKeySize:
+        return ed25519.PublicKey(raw), nil, nil
+    case 2 + minisignKeyIDLen + ed25519.PublicKeySize:
+        if string(raw[:2]) != minisignAlgEd {
+            return nil, nil, fmt.Errorf("不支持的 minisign 公钥算法: %q", raw[:2])
+        }
+        return ed25519.PublicKey(raw[2+minisignKeyIDLen:]), raw[2 : 2+minisignKeyIDLen], nil
+    default:
+        return nil, nil, fmt.Errorf("公钥长度 %d 字节不合法", len(raw))
+    }
+}
+
+// verifyMinisign 校验 minisign 分离签名：先验证文件签名，再验证覆盖可信注释的全局签名。
+func verifyMinisign(pub ed25519.PublicKey, keyID, data, sigFile []byte) error {
+    lines := strings.Split(strings.ReplaceAll(string(sigFile), "\r\n", "\n"), "\n")
+    if len(lines) < 4 {
+        return fmt.Errorf("%w: minisign 签名文件格式不完整", ErrBadSignature)
+    }
+    sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+    if err != nil || len(sig) != 2+minisignKeyIDLen+ed25519.SignatureSize {
+        return fmt.Errorf("%w: minisign 签名格式不合法", ErrBadSignature)
+    }
+    alg, sigKeyID, fileSig := string(sig[:2]), sig[2:2+minisignKeyIDLen], sig[2+minisignKeyIDLen:]
+    if keyID != nil && !bytes.Equal(keyID, sigKeyID) {
+        return fmt.Errorf("%w: 签名密钥 ID 与配置的公钥不一致", ErrBadSignature)
+    }
+
+    message := data
+    switch alg {
+    case minisignAlgEd:
+    case minisignAlgHashedEd:
+        sum := blake2b.Sum512(data)
+        message = sum[:]
+    default:
+        return fmt.Errorf("%w: 不支持的 minisign 签名算法 %q", ErrBadSignature, alg)
+    }
+    if !ed25519.Verify(pub, message, fileSig) {
+        return fmt.Errorf("%w: 文件签名不匹配", ErrBadSignature)
+    }
+
+    const trustedPrefix = "trusted comment: "
+    if !strings.HasPrefix(lines[2], trustedPrefix) {
+        return fmt.Errorf("%w: 缺少可信注释", ErrBadSignature)
+    }
+    globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+    if err != nil || len(globalSig) != ed25519.SignatureSize {
+        return fmt.Errorf("%w: 全局签名格式不合法", ErrBadSignature)
+    }
+    trusted := append(append([]byte{}, fileSig...), strings.TrimPrefix(lines[2], trustedPrefix)...)
+    if !ed25519.Verify(pub, trusted, globalSig) {
+        return fmt.Errorf("%w: 可信注释签名不匹配", ErrBadSignature)
+    }
+    return nil
+}
+
+// verifyEd25519 校验对原始文件的 ed25519 签名，签名文件可为 64 字节二进制或其 base64 文本。
+func verifyEd25519(pub ed25519.PublicKey, data, sigFile []byte) error {
+    sig := sigFile
+    if len(sig) != ed25519.SignatureSize {
+        decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigFile)))
+        if err != nil || len(decoded) != ed25519.SignatureSize {
+            return fmt.Errorf("%w: ed25519 签名格式不合法", ErrBadSignature)
+        }
+        sig = decoded
+    }
+    if !ed25519.Verify(pub, data, sig) {
+        return fmt.Errorf("%w: 文件签名不匹配", ErrBadSignature)
+    }
+    return nil
+}
+
+// fetchSmall 下载校验和、签名、密钥等小文件，超过 limit 字节视为异常。
+func fetchSmall(client *http.Client, url string, limit int64) ([]byte, error) {
+    req, err := http.NewRequest(http.MethodGet, url, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("User-Agent", userAgent)
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+    }
+    body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(body)) > limit {
+        return nil, fmt.Errorf("文件超过 %d 字节上限", limit)
+    }
+    return body, nil
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+    "bytes"
+    "context"
+    "crypto/ed25519"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "golang.org/x/crypto/blake2b"
+
+    "ipservice/pkg/qqwry/qqwrytest"
+)
+
+var (
+    oldDat = qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据"))
+    newDat = qqwrytest.Build(qqwrytest.Sample("2024年10月23日IP数据"))
+)
+
+// serveFiles 启动按路径返回固定内容的文件服务器，未登记的路径返回 404。
+func serveFiles(t *testing.T, files map[string][]byte) *httptest.Server {
+    t.Helper()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, ok := files[r.URL.Path]
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        w.Write(body)
+    }))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// existingData 在临时目录写入旧版本数据文件并返回其路径。
+func existingData(t *testing.T) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    if err := os.WriteFile(path, oldDat, 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+// assertData 确认 path 的内容为 want，且没有遗留临时文件。
+func assertData(t *testing.T, path string, want []byte) {
+    t.Helper()
+    got, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(got, want) {
+        t.Fatalf("%s 的内容与期望不一致", path)
+    }
+    if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+        t.Fatalf("临时文件 %s.part 未清理", path)
+    }
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+func TestFetchPinnedSHA256(t *testing.T) {
+    srv := serveFiles(t, map[string][]byte{"/qqwry.dat": newDat})
+
+    t.Run("摘要一致", func(t *testing.T) {
+        path := existingData(t)
+        d := newDownloader(Source{URL: srv.URL + "/qqwry.dat", SHA256: strings.ToUpper(sha256Hex(newDat))})
+        if _, err := d.fetch(context.Background(), path); err != nil {
+            t.Fatal(err)
+        }
+        assertData(t, path, newDat)
+    })
+
+    t.Run("摘要不一致", func(t *testing.T) {
+        path := existingData(t)
+        d := newDownloader(Source{URL: srv.URL + "/qqwry.dat", SHA256: sha256Hex(oldDat)})
+        if _, err := d.fetch(context.Background(), path); !errors.Is(err, ErrChecksumMismatch) {
+            t.Fatalf("err = %v，期望 ErrChecksumMismatch", err)
+        }
+        assertData(t, path, oldDat)
+    })
+}
+
+func TestFetchChecksumFile(t *testing.T) {
+    other := sha256Hex([]byte("qqwry.rar"))
+    tests := []struct {
+        name string
+        sums string
+        ok   bool
+    }{
+        {"多条目按文件名匹配", "# SHA256SUMS\n" + other + "  qqwry.rar\n" + sha256Hex(newDat) + " *qqwry.dat\n" + other + "  README.md\n", true},
+        {"仅含摘要", sha256Hex(newDat) + "\n", true},
+        {"对应条目摘要不一致", sha256Hex(newDat) + "  qqwry.rar\n" + other + "  qqwry.dat\n", false},
+        {"多条目均不对应", sha256Hex(newDat) + "  a.dat\n" + other + "  b.dat\n", false},
+        {"没有合法摘要", "not a checksum\n", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            srv := serveFiles(t, map[string][]byte{
+                "/dist/qqwry.dat": newDat,
+                "/SHA256SUMS":     []byte(tt.sums),
+            })
+            path := existingData(t)
+            d := newDownloader(Source{URL: srv.URL + "/dist/qqwry.dat", SHA256URL: srv.URL + "/SHA256SUMS"})
+            _, err := d.fetch(context.Background(), path)
+            if tt.ok {
+                if err != nil {
+                    t.Fatal(err)
+                }
+                assertData(t, path, newDat)
+                return
+            }
+            if !errors.Is(err, ErrChecksumMismatch) {
+                t.Fatalf("err = %v，期望 ErrChecksumMismatch", err)
+            }
+            assertData(t, path, oldDat)
+        })
+    }
+}
+
+// signer 以固定密钥生成各格式的公钥与分离签名。
+type signer struct {
+    priv  ed25519.PrivateKey
+    keyID []byte
+}
+
+func newSigner(seed byte) signer {
+    return signer{
+        priv:  ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize)),
+        keyID: bytes.Repeat([]byte{seed}, minisignKeyIDLen),
+    }
+}
+
+func (s signer) rawPublicKey() string {
+    return base64.StdEncoding.EncodeToString(s.priv.Public().(ed25519.PublicKey))
+}
+
+func (s signer) minisignPublicKey() string {
+    raw := append(append([]byte(minisignAlgEd), s.keyID...), s.priv.Public().(ed25519.PublicKey)...)
+    return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+}
+
+// minisign 生成 minisign 签名文件，alg 为 minisignAlgEd（原始文件）或 minisignAlgHashedEd（BLAKE2b-512 摘要）。
+func (s signer) minisign(alg string, data []byte, trusted string) []byte {
+    message := data
+    if alg == minisignAlgHashedEd {
+        sum := blake2b.Sum512(data)
+        message = sum[:]
+    }
+    fileSig := ed25519.Sign(s.priv, message)
+    sig := append(append([]byte(alg), s.keyID...), fileSig...)
+    globalSig := ed25519.Sign(s.priv, append(append([]byte{}, fileSig...), trusted...))
+    return []byte("untrusted comment: signature from minisign secret key\n" +
+        base64.StdEncoding.EncodeToString(sig) + "\n" +
+        "trusted comment: " + trusted + "\n" +
+        base64.StdEncoding.EncodeToString(globalSig) + "\n")
+}
+
+func TestFetchSignature(t *testing.T) {
+    key, other := newSigner(1), newSigner(2)
+    const trusted = "timestamp:1729036800\tfile:qqwry.dat"
+    tampered := append([]byte{}, newDat...)
+    tampered[len(tampered)-1] ^= 0xff
+
+    tests := []struct {
+        name      string
+        publicKey string
+        sig       []byte
+        ok        bool
+    }{
+        {"minisign", key.minisignPublicKey(), key.minisign(minisignAlgEd, newDat, trusted), true},
+        {"minisign 预哈希", key.minisignPublicKey(), key.minisign(minisignAlgHashedEd, newDat, trusted), true},
+        {"minisign 签名内容被篡改", key.minisignPublicKey(), key.minisign(minisignAlgEd, tampered, trusted), false},
+        {"minisign 预哈希签名内容被篡改", key.minisignPublicKey(), key.minisign(minisignAlgHashedEd, tampered, trusted), false},
+        {"minisign 可信注释被篡改", key.minisignPublicKey(),
+            bytes.Replace(key.minisign(minisignAlgEd, newDat, trusted), []byte("qqwry.dat"), []byte("other.dat"), 1), false},
+        {"minisign 其他密钥签名", key.minisignPublicKey(), other.minisign(minisignAlgEd, newDat, trusted), false},
+        {"ed25519 二进制签名", key.rawPublicKey(), ed25519.Sign(key.priv, newDat), true},
+        {"ed25519 base64 签名", key.rawPublicKey(), []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key.priv, newDat)) + "\n"), true},
+        {"ed25519 签名内容被篡改", key.rawPublicKey(), ed25519.Sign(key.priv, tampered), false},
+        {"ed25519 其他密钥签名", key.rawPublicKey(), ed25519.Sign(other.priv, newDat), false},
+        {"ed25519 签名格式不合法", key.rawPublicKey(), []byte("not a signature"), false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            srv := serveFiles(t, map[string][]byte{
+                "/qqwry.dat":         newDat,
+                "/qqwry.dat.minisig": tt.sig,
+            })
+            path := existingData(t)
+            d := newDownloader(Source{
+                URL:          srv.URL + "/qqwry.dat",
+                PublicKey:    tt.publicKey,
+                SignatureURL: srv.URL + "/qqwry.dat.minisig",
+            })
+            _, err := d.fetch(context.Background(), path)
+            if tt.ok {
+                if err != nil {
+                    t.Fatal(err)
+                }
+                assertData(t, path, newDat)
+                return
+            }
+            if !errors.Is(err, ErrBadSignature) {
+                t.Fatalf("err = %v，期望 ErrBadSignature", err)
+            }
+            assertData(t, path, oldDat)
+        })
+    }
+}
@@ -0,0 +1,130 @@
+// Package i18n 提供面向用户文案的多语言目录，目前支持简体中文与英文。
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言，取值为 BCP 47 语言标签。
+const (
+	ZH = "zh-CN" // 默认语言
+	EN = "en"
+)
+
+// Default 为无法从请求中识别语言时使用的语言。
+const Default = ZH
+
+// localesFS 为内置的文案目录，每种语言一个 JSON 文件，键为文案标识。
+//
+//go:embed locales/*.json
+var localesFS embed.FS
+
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]string {
+	catalogs, err := loadCatalogs()
+	if err != nil {
+		panic(fmt.Sprintf("内置文案目录解析失败: %v", err))
+	}
+	return catalogs
+}
+
+// loadCatalogs 读取全部语言的文案目录，并校验各目录的文案标识一致，避免遗漏翻译。
+func loadCatalogs() (map[string]map[string]string, error) {
+	catalogs := make(map[string]map[string]string)
+	for _, lang := range []string{ZH, EN} {
+		data, err := localesFS.ReadFile("locales/" + lang + ".json")
+		if err != nil {
+			return nil, err
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("%s: %w", lang, err)
+		}
+		catalogs[lang] = catalog
+	}
+
+	base := catalogs[Default]
+	for lang, catalog := range catalogs {
+		var missing []string
+		for key := range base {
+			if _, ok := catalog[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := base[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return nil, fmt.Errorf("%s 与 %s 的文案标识不一致: %s", lang, Default, strings.Join(missing, ", "))
+		}
+	}
+	return catalogs, nil
+}
+
+// T 返回指定语言下的文案，args 非空时按 fmt.Sprintf 格式化；未知语言回退为默认语言，未知标识原样返回。
+func T(lang, key string, args ...any) string {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		catalog = catalogs[Default]
+	}
+	message, ok := catalog[key]
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Match 将语言标签（如 en-US、zh-Hans-CN）映射为支持的语言，无法识别时返回空字符串。
+func Match(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	switch primary {
+	case "zh":
+		return ZH
+	case "en":
+		return EN
+	}
+	return ""
+}
+
+// Negotiate 选择展示语言：优先使用 lang 参数，其次按 Accept-Language 的 q 值选择，均无法识别时返回默认语言。
+func Negotiate(param, acceptLanguage string) string {
+	if lang := Match(param); lang != "" {
+		return lang
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang := Match(tag)
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(k, "q") {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		// q 值相同时保持 Accept-Language 中的先后顺序
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	if best == "" {
+		return Default
+	}
+	return best
+}
@@ -0,0 +1,94 @@
+package ipdb
+
+import (
+    "encoding/binary"
+    "fmt"
+    "math/bits"
+    "net/netip"
+    "strings"
+
+    "ipservice/pkg/qqwry"
+)
+
+// Range 表示 qqwry 中的一条连续地址区段及其归属信息。
+type Range struct {
+    Start   uint32
+    End     uint32
+    Country string
+    Area    string
+}
+
+// StartIP 返回区段起始地址的点分十进制形式。
+func (r Range) StartIP() string {
+    return qqwry.FormatIPv4(r.Start)
+}
+
+// EndIP 返回区段结束地址的点分十进制形式。
+func (r Range) EndIP() string {
+    return qqwry.FormatIPv4(r.End)
+}
+
+// CIDRs 将区段拆分为最少数量的 CIDR 前缀。
+func (r Range) CIDRs() []string {
+    return rangeToCIDRs(r.Start, r.End)
+}
+
+// rangeOf 将 qqwry 记录转换为区段。
+func rangeOf(rec qqwry.Record) Range {
+    return Range{Start: rec.Start, End: rec.End, Country: rec.Country, Area: rec.Area}
+}
+
+// rangeToCIDRs 将闭区间 [start, end] 拆分为最少数量的 CIDR 前缀。
+func rangeToCIDRs(start, end uint32) []string {
+    var out []string
+    for {
+        // 前缀长度同时受起始地址对齐与剩余长度约束
+        size := bits.TrailingZeros32(start)
+        for size > 0 && uint64(start)+(uint64(1)<<size)-1 > uint64(end) {
+            size--
+        }
+        out = append(out, netip.PrefixFrom(addrFromUint32(start), 32-size).String())
+
+        next := uint64(start) + uint64(1)<<size
+        if next > uint64(end) {
+            return out
+        }
+        start = uint32(next)
+    }
+}
+
+// mergeRanges 合并首尾相接的区段（输入需按起始地址升序），返回合并后的 CIDR 列表。
+func mergeRanges(ranges []Range) []string {
+    var out []string
+    for i := 0; i < len(ranges); {
+        start, end := ranges[i].Start, ranges[i].End
+        j := i + 1
+        for j < len(ranges) && end != ^uint32(0) && ranges[j].Start == end+1 {
+            end = ranges[j].End
+            j++
+        }
+        out = append(out, rangeToCIDRs(start, end)...)
+        i = j
+    }
+    return out
+}
+
+func addrFromUint32(v uint32) netip.Addr {
+    return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+}
+
+// parseCIDR 解析 IPv4 前缀并返回其覆盖的首尾地址，主机位会被自动清零。
+func parseCIDR(cidr string) (netip.Prefix, uint32, uint32, error) {
+    prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+    if err != nil {
+        return netip.Prefix{}, 0, 0, fmt.Errorf("%w: 无法解析网段: %s", ErrInvalidCIDR, cidr)
+    }
+    if !prefix.Addr().Is4() {
+        return netip.Prefix{}, 0, 0, fmt.Errorf("%w: 当前qqwry.dat仅支持IPv4查询", ErrIPv6NotSupported)
+    }
+    prefix = prefix.Masked()
+    a := prefix.Addr().As4()
+    first := binary.BigEndian.Uint32(a[:])
+    last := first | uint32(uint64(1)<<(32-prefix.Bits())-1)
+    return prefix, first, last, nil
+}
@@ -0,0 +1,106 @@
+package ipdb
+
+import (
+    "bytes"
+    _ "embed"
+    "encoding/csv"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// countriesCSV 为内置的 ISO 3166-1 国家/地区对照表（alpha-2、洲代码、中英文名称及别名）。
+//
+//go:embed data/countries.csv
+var countriesCSV []byte
+
+// Country 表示 ISO 3166-1 中的一个国家或地区。
+type Country struct {
+    Code      string // ISO 3166-1 alpha-2
+    Continent string // AF/AN/AS/EU/NA/OC/SA
+    NameEN    string
+    NameZH    string
+}
+
+// countryTable 维护中文名称（含别名）到国家的映射，按名称长度倒序做最长前缀匹配。
+type countryTable struct {
+    byCode map[string]*Country
+    names  []string
+    byName map[string]*Country
+}
+
+var countries = mustLoadCountries(countriesCSV)
+
+// regionCountryCodes 将港澳台的省级 adcode 前缀映射为各自的 ISO 代码。
+var regionCountryCodes = map[string]string{
+    "71": "TW",
+    "81": "HK",
+    "82": "MO",
+}
+
+func mustLoadCountries(data []byte) *countryTable {
+    table, err := loadCountries(data)
+    if err != nil {
+        panic(fmt.Sprintf("内置国家代码数据解析失败: %v", err))
+    }
+    return table
+}
+
+func loadCountries(data []byte) (*countryTable, error) {
+    records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    if len(records) < 2 {
+        return nil, fmt.Errorf("国家代码数据为空")
+    }
+
+    table := &countryTable{
+        byCode: make(map[string]*Country),
+        byName: make(map[string]*Country),
+    }
+    for i, rec := range records[1:] {
+        if len(rec) != 5 {
+            return nil, fmt.Errorf("第 %d 行字段数量异常", i+2)
+        }
+        country := &Country{Code: rec[0], Continent: rec[1], NameEN: rec[2], NameZH: rec[3]}
+        if len(country.Code) != 2 {
+            return nil, fmt.Errorf("第 %d 行国家代码不合法: %s", i+2, country.Code)
+        }
+        table.byCode[country.Code] = country
+
+        names := []string{country.NameZH}
+        if rec[4] != "" {
+            names = append(names, strings.Split(rec[4], "|")...)
+        }
+        for _, name := range names {
+            if _, dup := table.byName[name]; dup {
+                return nil, fmt.Errorf("第 %d 行名称重复: %s", i+2, name)
+            }
+            table.byName[name] = country
+            table.names = append(table.names, name)
+        }
+    }
+    sort.SliceStable(table.names, func(i, j int) bool {
+        return len(table.names[i]) > len(table.names[j])
+    })
+    return table, nil
+}
+
+// match 将 qqwry 的国家字段解析为 ISO 国家；国内省市归入 CN，港澳台按各自代码处理。
+func (t *countryTable) match(country string, province *Region) *Country {
+    if province != nil {
+        if code, ok := regionCountryCodes[province.Adcode[:2]]; ok {
+            return t.byCode[code]
+        }
+        return t.byCode["CN"]
+    }
+
+    value := trimRegionSeparators(country)
+    for _, name := range t.names {
+        if strings.HasPrefix(value, name) {
+            return t.byName[name]
+        }
+    }
+    return nil
+}
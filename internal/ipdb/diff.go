@@ -0,0 +1,197 @@
+package ipdb
+
+import (
+    "fmt"
+
+    "ipservice/pkg/qqwry"
+)
+
+// DiffKind 表示两个数据版本之间某一地址区段的变化类型。
+type DiffKind string
+
+const (
+    DiffAdded   DiffKind = "added"
+    DiffRemoved DiffKind = "removed"
+    DiffChanged DiffKind = "changed"
+)
+
+// DiffEntry 描述一段发生变化的地址区段及其变更前后的归属信息。
+type DiffEntry struct {
+    Kind       DiffKind
+    Start      uint32
+    End        uint32
+    OldCountry string
+    OldArea    string
+    NewCountry string
+    NewArea    string
+}
+
+// StartIP 返回区段起始地址的点分十进制形式。
+func (e DiffEntry) StartIP() string {
+    return qqwry.FormatIPv4(e.Start)
+}
+
+// EndIP 返回区段结束地址的点分十进制形式。
+func (e DiffEntry) EndIP() string {
+    return qqwry.FormatIPv4(e.End)
+}
+
+// DiffSummary 汇总两个版本的记录规模与各类变化涉及的区段数、地址数。
+type DiffSummary struct {
+    OldVersion string
+    NewVersion string
+    OldRecords int
+    NewRecords int
+
+    Added      int
+    Removed    int
+    Changed    int
+    AddedIPs   uint64
+    RemovedIPs uint64
+    ChangedIPs uint64
+}
+
+// DiffReport 为两个 qqwry.dat 的完整对比结果，Entries 按起始地址升序排列。
+type DiffReport struct {
+    Summary DiffSummary
+    Entries []DiffEntry
+}
+
+// DiffFiles 加载两个数据文件并对比差异，便于在热更新前审阅数据变化。
+// 末尾的版本记录每次发布都会变化，仅体现在汇总的版本信息中，不计入变化明细。
+func DiffFiles(oldPath, newPath string) (*DiffReport, error) {
+    oldRanges, oldMeta, err := loadRanges(oldPath)
+    if err != nil {
+        return nil, fmt.Errorf("加载旧数据失败: %w", err)
+    }
+    newRanges, newMeta, err := loadRanges(newPath)
+    if err != nil {
+        return nil, fmt.Errorf("加载新数据失败: %w", err)
+    }
+    report := Diff(oldRanges, newRanges)
+    report.Summary.OldVersion = oldMeta.Version
+    report.Summary.NewVersion = newMeta.Version
+    report.Summary.OldRecords = oldMeta.Records
+    report.Summary.NewRecords = newMeta.Records
+    return report, nil
+}
+
+// Diff 顺序遍历两份按起始地址升序排列的区段列表，在双方边界的并集上切分并比较归属信息，
+// 连续且变化内容相同的片段会被合并为一条。汇总中的版本描述留空，由调用方按需填写。
+func Diff(oldRanges, newRanges []Range) *DiffReport {
+    report := &DiffReport{Summary: DiffSummary{
+        OldRecords: len(oldRanges),
+        NewRecords: len(newRanges),
+    }}
+
+    i, j := 0, 0
+    pos := uint64(0)
+    for pos <= maxIPv4 && (i < len(oldRanges) || j < len(newRanges)) {
+        // 跳过已经完全位于当前位置之前的区段
+        for i < len(oldRanges) && uint64(oldRanges[i].End) < pos {
+            i++
+        }
+        for j < len(newRanges) && uint64(newRanges[j].End) < pos {
+            j++
+        }
+
+        var oldRec, newRec *Range
+        segEnd := maxIPv4
+        if i < len(oldRanges) {
+            if r := &oldRanges[i]; uint64(r.Start) <= pos {
+                oldRec = r
+                segEnd = min(segEnd, uint64(r.End))
+            } else {
+                segEnd = min(segEnd, uint64(r.Start)-1)
+            }
+        }
+        if j < len(newRanges) {
+            if r := &newRanges[j]; uint64(r.Start) <= pos {
+                newRec = r
+                segEnd = min(segEnd, uint64(r.End))
+            } else {
+                segEnd = min(segEnd, uint64(r.Start)-1)
+            }
+        }
+
+        entry := DiffEntry{Start: uint32(pos), End: uint32(segEnd)}
+        switch {
+        case oldRec != nil && newRec != nil:
+            if oldRec.Country != newRec.Country || oldRec.Area != newRec.Area {
+                entry.Kind = DiffChanged
+            }
+        case newRec != nil:
+            entry.Kind = DiffAdded
+        case oldRec != nil:
+            entry.Kind = DiffRemoved
+        }
+        if entry.Kind != "" {
+            if oldRec != nil {
+                entry.OldCountry, entry.OldArea = oldRec.Country, oldRec.Area
+            }
+            if newRec != nil {
+                entry.NewCountry, entry.NewArea = newRec.Country, newRec.Area
+            }
+            report.add(entry)
+        }
+        pos = segEnd + 1
+    }
+    return report
+}
+
+const maxIPv4 = uint64(^uint32(0))
+
+// add 记录一条变化片段；与上一条首尾相接且内容一致时直接延长。
+func (r *DiffReport) add(e DiffEntry) {
+    size := uint64(e.End) - uint64(e.Start) + 1
+    switch e.Kind {
+    case DiffAdded:
+        r.Summary.AddedIPs += size
+    case DiffRemoved:
+        r.Summary.RemovedIPs += size
+    case DiffChanged:
+        r.Summary.ChangedIPs += size
+    }
+
+    if n := len(r.Entries); n > 0 {
+        last := &r.Entries[n-1]
+        if uint64(last.End)+1 == uint64(e.Start) && last.Kind == e.Kind &&
+            last.OldCountry == e.OldCountry && last.OldArea == e.OldArea &&
+            last.NewCountry == e.NewCountry && last.NewArea == e.NewArea {
+            last.End = e.End
+            return
+        }
+    }
+
+    switch e.Kind {
+    case DiffAdded:
+        r.Summary.Added++
+    case DiffRemoved:
+        r.Summary.Removed++
+    case DiffChanged:
+        r.Summary.Changed++
+    }
+    r.Entries = append(r.Entries, e)
+}
+
+// loadRanges 读取数据文件中除版本记录以外的全部区段，并返回文件元信息。
+func loadRanges(path string) ([]Range, qqwry.Metadata, error) {
+    reader, err := qqwry.Open(path)
+    if err != nil {
+        return nil, qqwry.Metadata{}, err
+    }
+    meta := reader.Metadata()
+    var ranges []Range
+    it := reader.Iterate()
+    for it.Next() {
+        rec := it.Record()
+        if meta.Version != "" && rec == meta.VersionRecord {
+            continue
+        }
+        ranges = append(ranges, rangeOf(rec))
+    }
+    if err := it.Err(); err != nil {
+        return nil, qqwry.Metadata{}, err
+    }
+    return ranges, meta, nil
+}
@@ -0,0 +1,59 @@
+package ipdb
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "ipservice/pkg/qqwry/qqwrytest"
+)
+
+// writeFixture 将样例记录写入临时目录并返回文件路径。
+func writeFixture(t testing.TB, records []qqwrytest.Record) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "qqwry.dat")
+    if err := os.WriteFile(path, qqwrytest.Build(records), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestDiffFilesIgnoresVersionRecord(t *testing.T) {
+    oldPath := writeFixture(t, qqwrytest.Sample("2024年10月16日IP数据"))
+    newPath := writeFixture(t, qqwrytest.Sample("2024年10月23日IP数据"))
+
+    report, err := DiffFiles(oldPath, newPath)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(report.Entries) != 0 {
+        t.Fatalf("仅版本记录不同时不应有变化明细，得到 %+v", report.Entries)
+    }
+    s := report.Summary
+    if s.OldVersion != "2024年10月16日IP数据" || s.NewVersion != "2024年10月23日IP数据" {
+        t.Fatalf("版本 = %q / %q", s.OldVersion, s.NewVersion)
+    }
+    if s.OldRecords != 22 || s.NewRecords != 22 {
+        t.Fatalf("记录数 = %d / %d，期望与文件一致", s.OldRecords, s.NewRecords)
+    }
+}
+
+func TestDiffFilesReportsChanges(t *testing.T) {
+    oldPath := writeFixture(t, qqwrytest.Sample("2024年10月16日IP数据"))
+    records := qqwrytest.Sample("2024年10月23日IP数据")
+    records[5].Country = "江苏省无锡市"
+    newPath := writeFixture(t, records)
+
+    report, err := DiffFiles(oldPath, newPath)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(report.Entries) != 1 {
+        t.Fatalf("期望 1 条变化，得到 %+v", report.Entries)
+    }
+    e := report.Entries[0]
+    if e.Kind != DiffChanged || e.StartIP() != "1.2.2.0" || e.EndIP() != "1.2.127.255" ||
+        e.OldCountry != "江苏省苏州市" || e.NewCountry != "江苏省无锡市" {
+        t.Fatalf("变化明细不符: %+v", e)
+    }
+}
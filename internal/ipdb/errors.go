@@ -1,12 +1,35 @@
 package ipdb
 
-import "errors"
+import (
+    "errors"
 
-// 领域错误：用于跨包判定与 HTTP 映射
+    "ipservice/pkg/qqwry"
+)
+
+// 领域错误：用于跨包判定与 HTTP 映射，数据文件相关的错误与 qqwry 包共用同一实例
 var (
-    ErrInvalidIP        = errors.New("invalid ip")
-    ErrIPv6NotSupported = errors.New("ipv6 not supported")
-    ErrNotFound         = errors.New("ip not found")
-    ErrDecodeCountry    = errors.New("decode country failed")
-    ErrDecodeArea       = errors.New("decode area failed")
+    ErrInvalidIP        = qqwry.ErrInvalidIP
+    ErrIPv6NotSupported = qqwry.ErrIPv6NotSupported
+    ErrNotFound         = qqwry.ErrNotFound
+    ErrDecodeCountry    = qqwry.ErrDecodeCountry
+    ErrDecodeArea       = qqwry.ErrDecodeArea
+    ErrInvalidQuery     = errors.New("invalid query")
+    ErrInvalidCIDR      = errors.New("invalid cidr")
+    ErrCorruptData      = qqwry.ErrCorruptData
 )
+
+// CorruptError 描述数据文件中某一偏移处的结构错误，可通过 errors.Is 匹配 ErrCorruptData。
+type CorruptError = qqwry.CorruptError
+
+// ValidationError 汇总一次完整校验中发现的全部结构问题。
+type ValidationError = qqwry.ValidationError
+
+// ValidateFile 加载并完整校验数据文件，常用于替换线上文件前的结构检查。
+func ValidateFile(path string) error {
+    return qqwry.ValidateFile(path)
+}
+
+// ValidateBytes 对内存中的数据做完整结构校验。
+func ValidateBytes(data []byte) error {
+    return qqwry.ValidateBytes(data)
+}
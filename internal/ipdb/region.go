@@ -0,0 +1,168 @@
+package ipdb
+
+import (
+    "bytes"
+    _ "embed"
+    "encoding/csv"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// regionsCSV 为内置的中国行政区划数据（GB/T 2260 代码 + 行政中心经纬度 + 英文名称，英文名称目前仅覆盖省级区划）。
+//
+//go:embed data/regions.csv
+var regionsCSV []byte
+
+// Region 表示一个省级或地级行政区划。
+type Region struct {
+    Adcode    string
+    Name      string
+    Short     string
+    NameEN    string // 英文名称，未收录时为空
+    Latitude  float64
+    Longitude float64
+}
+
+// regionTable 保存省级区划及其下辖地级区划，按简称长度倒序便于最长前缀匹配。
+type regionTable struct {
+    provinces []*Region
+    cities    map[string][]*Region // 省级 adcode 前两位 -> 地级区划
+    all       []*Region            // 全部地级区划，用于缺少省份前缀时兜底匹配
+}
+
+var regions = mustLoadRegions(regionsCSV)
+
+// regionSeparators 为 qqwry 新版数据中常见的层级分隔符。
+var regionSeparators = []string{"–", "—", "-", "|", " "}
+
+func mustLoadRegions(data []byte) *regionTable {
+    table, err := loadRegions(data)
+    if err != nil {
+        panic(fmt.Sprintf("内置行政区划数据解析失败: %v", err))
+    }
+    return table
+}
+
+func loadRegions(data []byte) (*regionTable, error) {
+    records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    if len(records) < 2 {
+        return nil, fmt.Errorf("行政区划数据为空")
+    }
+
+    table := &regionTable{cities: make(map[string][]*Region)}
+    for i, rec := range records[1:] {
+        if len(rec) != 6 {
+            return nil, fmt.Errorf("第 %d 行字段数量异常", i+2)
+        }
+        lat, err := strconv.ParseFloat(rec[3], 64)
+        if err != nil {
+            return nil, fmt.Errorf("第 %d 行纬度不合法: %w", i+2, err)
+        }
+        lon, err := strconv.ParseFloat(rec[4], 64)
+        if err != nil {
+            return nil, fmt.Errorf("第 %d 行经度不合法: %w", i+2, err)
+        }
+        region := &Region{Adcode: rec[0], Name: rec[1], Short: rec[2], Latitude: lat, Longitude: lon, NameEN: rec[5]}
+        if len(region.Adcode) != 6 {
+            return nil, fmt.Errorf("第 %d 行 adcode 不合法: %s", i+2, region.Adcode)
+        }
+        if strings.HasSuffix(region.Adcode, "0000") {
+            table.provinces = append(table.provinces, region)
+            continue
+        }
+        prefix := region.Adcode[:2]
+        table.cities[prefix] = append(table.cities[prefix], region)
+        table.all = append(table.all, region)
+    }
+
+    byShortLen := func(list []*Region) {
+        sort.SliceStable(list, func(i, j int) bool {
+            return utf8.RuneCountInString(list[i].Short) > utf8.RuneCountInString(list[j].Short)
+        })
+    }
+    byShortLen(table.provinces)
+    byShortLen(table.all)
+    for _, list := range table.cities {
+        byShortLen(list)
+    }
+    return table, nil
+}
+
+// Regions 返回内置的全部省级与地级区划，可用于绘制分布图等场景。
+func Regions() []Region {
+    out := make([]Region, 0, len(regions.provinces)+len(regions.all))
+    for _, list := range [][]*Region{regions.provinces, regions.all} {
+        for _, r := range list {
+            out = append(out, *r)
+        }
+    }
+    return out
+}
+
+// match 从 qqwry 的国家字段中解析省级与地级区划，无法识别时返回 nil。
+func (t *regionTable) match(country string) (province, city *Region) {
+    rest := trimRegionSeparators(country)
+    rest = trimRegionSeparators(strings.TrimPrefix(rest, "中国"))
+    if rest == "" {
+        return nil, nil
+    }
+
+    for _, p := range t.provinces {
+        switch {
+        case strings.HasPrefix(rest, p.Name):
+            rest = rest[len(p.Name):]
+        case strings.HasPrefix(rest, p.Short):
+            rest = rest[len(p.Short):]
+        default:
+            continue
+        }
+        province = p
+        break
+    }
+
+    if province == nil {
+        // 部分记录省略了省份，仅在简称足够长时兜底匹配地级区划，降低误判概率
+        for _, c := range t.all {
+            if utf8.RuneCountInString(c.Short) >= 2 && strings.HasPrefix(rest, c.Short) {
+                return t.provinceOf(c), c
+            }
+        }
+        return nil, nil
+    }
+
+    rest = trimRegionSeparators(rest)
+    for _, c := range t.cities[province.Adcode[:2]] {
+        if strings.HasPrefix(rest, c.Short) {
+            return province, c
+        }
+    }
+    return province, nil
+}
+
+func (t *regionTable) provinceOf(city *Region) *Region {
+    for _, p := range t.provinces {
+        if p.Adcode[:2] == city.Adcode[:2] {
+            return p
+        }
+    }
+    return nil
+}
+
+func trimRegionSeparators(s string) string {
+    for {
+        trimmed := s
+        for _, sep := range regionSeparators {
+            trimmed = strings.TrimPrefix(trimmed, sep)
+        }
+        if trimmed == s {
+            return s
+        }
+        s = trimmed
+    }
+}
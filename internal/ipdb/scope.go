@@ -0,0 +1,50 @@
+package ipdb
+
+import "net/netip"
+
+// Scope 表示地址在 IANA 特殊用途地址登记表中的归类，便于调用方按机器可读的值分支处理。
+type Scope string
+
+const (
+    ScopePublic    Scope = "public"
+    ScopePrivate   Scope = "private"
+    ScopeLoopback  Scope = "loopback"
+    ScopeCGNAT     Scope = "cgnat"
+    ScopeLinkLocal Scope = "link-local"
+    ScopeMulticast Scope = "multicast"
+    ScopeReserved  Scope = "reserved"
+)
+
+// specialPurposeRanges 摘自 IANA IPv4 Special-Purpose Address Registry 与 Multicast 登记表；
+// 文档、基准测试等不可全局路由的网段统一归为 reserved。
+var specialPurposeRanges = []struct {
+    prefix netip.Prefix
+    scope  Scope
+}{
+    {netip.MustParsePrefix("0.0.0.0/8"), ScopeReserved},       // RFC 791 "This network"
+    {netip.MustParsePrefix("10.0.0.0/8"), ScopePrivate},       // RFC 1918
+    {netip.MustParsePrefix("100.64.0.0/10"), ScopeCGNAT},      // RFC 6598
+    {netip.MustParsePrefix("127.0.0.0/8"), ScopeLoopback},     // RFC 1122
+    {netip.MustParsePrefix("169.254.0.0/16"), ScopeLinkLocal}, // RFC 3927
+    {netip.MustParsePrefix("172.16.0.0/12"), ScopePrivate},    // RFC 1918
+    {netip.MustParsePrefix("192.0.0.0/24"), ScopeReserved},    // RFC 6890 IETF 协议分配
+    {netip.MustParsePrefix("192.0.2.0/24"), ScopeReserved},    // RFC 5737 TEST-NET-1
+    {netip.MustParsePrefix("192.88.99.0/24"), ScopeReserved},  // RFC 7526 已废弃的 6to4 中继
+    {netip.MustParsePrefix("192.168.0.0/16"), ScopePrivate},   // RFC 1918
+    {netip.MustParsePrefix("198.18.0.0/15"), ScopeReserved},   // RFC 2544 基准测试
+    {netip.MustParsePrefix("198.51.100.0/24"), ScopeReserved}, // RFC 5737 TEST-NET-2
+    {netip.MustParsePrefix("203.0.113.0/24"), ScopeReserved},  // RFC 5737 TEST-NET-3
+    {netip.MustParsePrefix("224.0.0.0/4"), ScopeMulticast},    // RFC 5771
+    {netip.MustParsePrefix("240.0.0.0/4"), ScopeReserved},     // RFC 1112，含 255.255.255.255 受限广播
+}
+
+// ClassifyScope 返回 IPv4 地址所属的特殊用途分类，未命中任何登记网段时为 public。
+func ClassifyScope(ip uint32) Scope {
+    addr := addrFromUint32(ip)
+    for _, r := range specialPurposeRanges {
+        if r.prefix.Contains(addr) {
+            return r.scope
+        }
+    }
+    return ScopePublic
+}
@@ -0,0 +1,185 @@
+package ipdb
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "ipservice/pkg/qqwry"
+)
+
+const (
+    defaultSearchPageSize = 50
+    maxSearchPageSize     = 1000
+)
+
+// SearchQuery 描述一次反向检索的条件，国家与区域按子串匹配，至少提供其一。
+// Page、PageSize 为零时分别取第 1 页与每页 50 条，PageSize 不能超过 1000。
+type SearchQuery struct {
+    Country  string
+    Area     string
+    Page     int
+    PageSize int
+}
+
+// SearchResult 为分页后的检索结果，CIDRs 为当前页区段合并后的前缀列表。
+type SearchResult struct {
+    Total    int
+    Page     int
+    PageSize int
+    Ranges   []Range
+    CIDRs    []string
+}
+
+// searchIndex 在加载时构建，记录归一化后的国家/区域字符串到区段的倒排关系。
+type searchIndex struct {
+    ranges    []indexedRange
+    countries stringPool
+    areas     stringPool
+}
+
+type indexedRange struct {
+    start, end uint32
+    country    int32
+    area       int32
+}
+
+// stringPool 对解码后的字符串去重，并为每个取值维护检索键与倒排列表。
+type stringPool struct {
+    ids      map[string]int32
+    values   []string
+    keys     []string
+    postings [][]int32
+}
+
+func (p *stringPool) add(value string, record int32) int32 {
+    id, ok := p.ids[value]
+    if !ok {
+        id = int32(len(p.values))
+        p.ids[value] = id
+        p.values = append(p.values, value)
+        p.keys = append(p.keys, searchKey(value))
+        p.postings = append(p.postings, nil)
+    }
+    p.postings[id] = append(p.postings[id], record)
+    return id
+}
+
+// match 返回检索键包含 query 的全部取值；query 为空时返回 nil 表示不限制。
+func (p *stringPool) match(query string) map[int32]bool {
+    if query == "" {
+        return nil
+    }
+    matched := make(map[int32]bool)
+    for id, key := range p.keys {
+        if strings.Contains(key, query) {
+            matched[int32(id)] = true
+        }
+    }
+    return matched
+}
+
+// buildSearchIndex 遍历全部记录，构建反向检索索引。
+func buildSearchIndex(reader *qqwry.Reader) (*searchIndex, error) {
+    idx := &searchIndex{
+        countries: stringPool{ids: make(map[string]int32)},
+        areas:     stringPool{ids: make(map[string]int32)},
+    }
+    it := reader.Iterate()
+    for it.Next() {
+        rec := it.Record()
+        record := int32(len(idx.ranges))
+        idx.ranges = append(idx.ranges, indexedRange{
+            start:   rec.Start,
+            end:     rec.End,
+            country: idx.countries.add(rec.Country, record),
+            area:    idx.areas.add(rec.Area, record),
+        })
+    }
+    if err := it.Err(); err != nil {
+        return nil, err
+    }
+    return idx, nil
+}
+
+// search 按条件筛选区段并分页。
+func (idx *searchIndex) search(q SearchQuery) (SearchResult, error) {
+    countryQuery := searchKey(q.Country)
+    areaQuery := searchKey(q.Area)
+    if countryQuery == "" && areaQuery == "" {
+        return SearchResult{}, fmt.Errorf("%w: 国家与区域条件至少提供一个", ErrInvalidQuery)
+    }
+
+    // 页码与每页条数为零时取默认值
+    page, pageSize := q.Page, q.PageSize
+    if page == 0 {
+        page = 1
+    }
+    if pageSize == 0 {
+        pageSize = defaultSearchPageSize
+    }
+    if page < 0 {
+        return SearchResult{}, fmt.Errorf("%w: 页码 %d 不合法", ErrInvalidQuery, page)
+    }
+    if pageSize < 0 || pageSize > maxSearchPageSize {
+        return SearchResult{}, fmt.Errorf("%w: 每页条数需在 1 到 %d 之间", ErrInvalidQuery, maxSearchPageSize)
+    }
+
+    countryIDs := idx.countries.match(countryQuery)
+    areaIDs := idx.areas.match(areaQuery)
+
+    // 从有条件的一侧收集候选记录，再用另一侧过滤
+    var candidates []int32
+    if countryIDs != nil {
+        for id := range countryIDs {
+            candidates = append(candidates, idx.countries.postings[id]...)
+        }
+    } else {
+        for id := range areaIDs {
+            candidates = append(candidates, idx.areas.postings[id]...)
+        }
+    }
+    sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+    hits := candidates[:0]
+    for _, record := range candidates {
+        r := idx.ranges[record]
+        if countryIDs != nil && !countryIDs[r.country] {
+            continue
+        }
+        if areaIDs != nil && !areaIDs[r.area] {
+            continue
+        }
+        hits = append(hits, record)
+    }
+
+    // 先按页数比较再计算偏移，避免页码过大时乘法溢出
+    result := SearchResult{Total: len(hits), Page: page, PageSize: pageSize}
+    if page-1 >= (len(hits)+pageSize-1)/pageSize {
+        return result, nil
+    }
+    from := (page - 1) * pageSize
+    to := min(from+pageSize, len(hits))
+    for _, record := range hits[from:to] {
+        r := idx.ranges[record]
+        result.Ranges = append(result.Ranges, Range{
+            Start:   r.start,
+            End:     r.end,
+            Country: idx.countries.values[r.country],
+            Area:    idx.areas.values[r.area],
+        })
+    }
+    result.CIDRs = mergeRanges(result.Ranges)
+    return result, nil
+}
+
+// searchKey 归一化检索字符串：统一小写并去除空白与层级分隔符。
+func searchKey(value string) string {
+    return strings.Map(func(r rune) rune {
+        switch r {
+        case ' ', '\t', '-', '–', '—', '|':
+            return -1
+        }
+        return r
+    }, strings.ToLower(strings.TrimSpace(value)))
+}
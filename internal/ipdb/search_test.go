@@ -0,0 +1,46 @@
+package ipdb
+
+import (
+    "errors"
+    "math"
+    "testing"
+)
+
+func TestSearchPaging(t *testing.T) {
+    svc := newSampleService(t)
+
+    // 样例数据中国家含“江苏”的区段共 3 条
+    tests := []struct {
+        name     string
+        page     int
+        pageSize int
+        want     int // 当前页区段数，-1 表示期望 ErrInvalidQuery
+    }{
+        {"默认页码", 0, 2, 2},
+        {"最后一页", 2, 2, 1},
+        {"超出末页", 3, 2, 0},
+        {"负数页码", -1, 2, -1},
+        {"最大页码", math.MaxInt, 2, 0},
+        {"最大页码与每页上限", math.MaxInt, maxSearchPageSize, 0},
+        {"默认每页条数", 1, 0, 3},
+        {"负数每页条数", 1, -1, -1},
+        {"每页条数超过上限", 1, maxSearchPageSize + 1, -1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := svc.Search(SearchQuery{Country: "江苏", Page: tt.page, PageSize: tt.pageSize})
+            if tt.want < 0 {
+                if !errors.Is(err, ErrInvalidQuery) {
+                    t.Fatalf("err = %v，期望 ErrInvalidQuery", err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if result.Total != 3 || len(result.Ranges) != tt.want {
+                t.Fatalf("Total = %d，区段数 = %d，期望 3 与 %d", result.Total, len(result.Ranges), tt.want)
+            }
+        })
+    }
+}
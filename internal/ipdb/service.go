@@ -2,36 +2,123 @@ package ipdb
 
 import (
     "fmt"
-    "strings"
+    "net/netip"
     "sync"
+    "time"
+
+    "ipservice/pkg/qqwry"
 )
 
 // Result 表示一次 IP 查询的标准化结果。
 type Result struct {
+    // IP 为调用 Lookup 时传入的原始字符串；LookupAddr、LookupUint32 为避免分配不填充，可使用 Addr.String()
     IP      string
+    Addr    netip.Addr
     Country string
     Area    string
+    Scope   Scope
+
+    // RangeStart、RangeEnd 为命中的 qqwry 区段（闭区间），跳过数据库查询时均为零值
+    RangeStart uint32
+    RangeEnd   uint32
+
+    // 以下字段由内置行政区划数据补全，无法识别（如境外地址）时保持零值
+    Province   string
+    City       string
+    ProvinceEN string // 区划英文名称，未收录时为空
+    CityEN     string
+    Adcode     string
+    Latitude   float64
+    Longitude  float64
+
+    // ISO 3166-1 国家信息，无法映射（如保留地址）时保持零值
+    CountryCode   string
+    CountryNameEN string
+    CountryNameZH string
+    Continent     string
 }
 
 // Service 管理 qqwry 数据的加载与查询，并提供线程安全的对外接口。
 type Service struct {
     path string
 
+    // skipNonPublic 为 true 时，非公网地址仅返回 Scope，不再查询 qqwry
+    skipNonPublic bool
+
     mu     sync.RWMutex
-    reader *qqwryReader
+    reader *qqwry.Reader
+    index  *searchIndex
+    meta   Metadata
+}
+
+// Metadata 描述当前加载的数据文件。
+type Metadata struct {
+    Version  string // 数据版本描述，如“2024年10月16日IP数据”，无法识别时为空
+    Records  int    // 地址区段数量
+    Size     int64  // 文件大小（字节）
+    LoadedAt time.Time
+}
+
+// Option 用于定制 Service 的行为。
+type Option func(*Service)
+
+// WithSkipNonPublic 设置是否对私有、回环、组播等非公网地址跳过数据库查询。
+func WithSkipNonPublic(skip bool) Option {
+    return func(s *Service) {
+        s.skipNonPublic = skip
+    }
 }
 
 // NewService 创建服务实例并加载数据。
-func NewService(path string) (*Service, error) {
-    reader, err := newReader(path)
+func NewService(path string, opts ...Option) (*Service, error) {
+    reader, index, meta, err := load(path)
     if err != nil {
         return nil, err
     }
-    return &Service{path: path, reader: reader}, nil
+    s := &Service{path: path, reader: reader, index: index, meta: meta}
+    for _, opt := range opts {
+        opt(s)
+    }
+    return s, nil
 }
 
-// Lookup 返回指定 IP 的归属地信息。
+// Lookup 返回指定 IP 的归属地信息，并按 IANA 特殊用途登记表标注地址范围。
 func (s *Service) Lookup(ip string) (Result, error) {
+    target, err := qqwry.ParseIPv4(ip)
+    if err != nil {
+        return Result{}, err
+    }
+    result, err := s.lookup(target)
+    if err != nil {
+        return Result{}, err
+    }
+    result.IP = ip
+    return result, nil
+}
+
+// LookupAddr 与 Lookup 相同，但直接接收 netip.Addr，跳过字符串解析；IPv4 映射的 IPv6 地址按 IPv4 处理。
+// 命中时不产生内存分配，适合进程内的高频调用。
+func (s *Service) LookupAddr(addr netip.Addr) (Result, error) {
+    target, err := qqwry.AddrToUint32(addr)
+    if err != nil {
+        return Result{}, err
+    }
+    return s.lookup(target)
+}
+
+// LookupUint32 查询大端序整数形式的 IPv4 地址，命中时不产生内存分配。
+func (s *Service) LookupUint32(ip uint32) (Result, error) {
+    return s.lookup(ip)
+}
+
+// lookup 为各查询入口共用的实现，返回的字符串均来自 qqwry 驻留表与内置区划、国家数据，不产生分配。
+func (s *Service) lookup(target uint32) (Result, error) {
+    addr := addrFromUint32(target)
+    scope := ClassifyScope(target)
+    if scope != ScopePublic && s.skipNonPublic {
+        return Result{Addr: addr, Scope: scope}, nil
+    }
+
     s.mu.RLock()
     reader := s.reader
     s.mu.RUnlock()
@@ -40,47 +127,141 @@ func (s *Service) Lookup(ip string) (Result, error) {
         return Result{}, fmt.Errorf("qqwry 数据尚未加载")
     }
 
-    countryRaw, areaRaw, err := reader.lookupRaw(ip)
+    rec, err := reader.LookupUint32(target)
     if err != nil {
         return Result{}, err
     }
 
-    country, err := decodeGBK(countryRaw)
-    if err != nil {
-        return Result{}, fmt.Errorf("%w: 国家字段编码转换失败: %v", ErrDecodeCountry, err)
+    result := Result{
+        Addr:       addr,
+        Country:    rec.Country,
+        Area:       rec.Area,
+        Scope:      scope,
+        RangeStart: rec.Start,
+        RangeEnd:   rec.End,
     }
-    area, err := decodeGBK(areaRaw)
+    province, city := regions.match(rec.Country)
+    result.applyRegion(province, city)
+    result.applyCountry(countries.match(rec.Country, province))
+    return result, nil
+}
+
+// Overlap 为网段查询结果，Truncated 表示因数量上限未返回全部记录。
+type Overlap struct {
+    Prefix    string
+    Ranges    []Range
+    Truncated bool
+}
+
+// Overlapping 返回与指定 IPv4 网段重叠的全部 qqwry 记录，用于查看网段的细分情况；
+// limit > 0 时最多返回 limit 条。
+func (s *Service) Overlapping(cidr string, limit int) (Overlap, error) {
+    prefix, first, last, err := parseCIDR(cidr)
     if err != nil {
-        return Result{}, fmt.Errorf("%w: 区域字段编码转换失败: %v", ErrDecodeArea, err)
+        return Overlap{}, err
     }
 
-    country = normalize(country)
-    area = normalize(area)
+    s.mu.RLock()
+    reader := s.reader
+    s.mu.RUnlock()
 
-    return Result{
-        IP:      ip,
-        Country: country,
-        Area:    area,
-    }, nil
+    if reader == nil {
+        return Overlap{}, fmt.Errorf("qqwry 数据尚未加载")
+    }
+
+    out := Overlap{Prefix: prefix.String()}
+    it := reader.IterateRange(first, last)
+    for it.Next() {
+        if limit > 0 && len(out.Ranges) >= limit {
+            out.Truncated = true
+            break
+        }
+        out.Ranges = append(out.Ranges, rangeOf(it.Record()))
+    }
+    if err := it.Err(); err != nil {
+        return Overlap{}, err
+    }
+    return out, nil
+}
+
+// Search 按国家/区域子串反向检索匹配的地址区段。
+func (s *Service) Search(q SearchQuery) (SearchResult, error) {
+    s.mu.RLock()
+    index := s.index
+    s.mu.RUnlock()
+
+    if index == nil {
+        return SearchResult{}, fmt.Errorf("qqwry 数据尚未加载")
+    }
+    return index.search(q)
+}
+
+// Metadata 返回当前加载的数据文件信息。
+func (s *Service) Metadata() Metadata {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.meta
 }
 
 // Reload 重新加载数据文件，便于热更新。
 func (s *Service) Reload() error {
-    reader, err := newReader(s.path)
+    reader, index, meta, err := load(s.path)
     if err != nil {
         return err
     }
 
     s.mu.Lock()
     s.reader = reader
+    s.index = index
+    s.meta = meta
     s.mu.Unlock()
     return nil
 }
 
-func normalize(value string) string {
-    cleaned := strings.TrimSpace(value)
-    if cleaned == "" || strings.EqualFold(cleaned, "CZ88.NET") {
-        return ""
+// load 读取数据文件并同步构建反向检索索引与元信息。
+func load(path string) (*qqwry.Reader, *searchIndex, Metadata, error) {
+    reader, err := qqwry.Open(path)
+    if err != nil {
+        return nil, nil, Metadata{}, err
+    }
+    index, err := buildSearchIndex(reader)
+    if err != nil {
+        return nil, nil, Metadata{}, fmt.Errorf("构建检索索引失败: %w", err)
+    }
+    meta := reader.Metadata()
+    return reader, index, Metadata{
+        Version:  meta.Version,
+        Records:  meta.Records,
+        Size:     meta.Size,
+        LoadedAt: time.Now(),
+    }, nil
+}
+
+// applyRegion 将匹配到的行政区划写入结果，优先使用地级区划的代码与坐标。
+func (r *Result) applyRegion(province, city *Region) {
+    if province == nil {
+        return
+    }
+    r.Province = province.Name
+    r.ProvinceEN = province.NameEN
+    target := province
+    if city != nil {
+        r.City = city.Name
+        r.CityEN = city.NameEN
+        target = city
+    }
+    r.Adcode = target.Adcode
+    r.Latitude = target.Latitude
+    r.Longitude = target.Longitude
+}
+
+// applyCountry 写入 ISO 3166-1 国家代码、中英文规范名称与洲代码。
+func (r *Result) applyCountry(country *Country) {
+    if country == nil {
+        return
     }
-    return cleaned
+    r.CountryCode = country.Code
+    r.CountryNameEN = country.NameEN
+    r.CountryNameZH = country.NameZH
+    r.Continent = country.Continent
 }
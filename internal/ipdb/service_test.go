@@ -0,0 +1,129 @@
+package ipdb
+
+import (
+    "net/netip"
+    "testing"
+
+    "ipservice/pkg/qqwry"
+    "ipservice/pkg/qqwry/qqwrytest"
+)
+
+// 测量使用的地址分别命中国内区段（需补全行政区划）与境外区段
+var benchIPs = []string{"1.2.3.4", "8.8.8.8"}
+
+func newSampleService(t testing.TB) *Service {
+    t.Helper()
+    svc, err := NewService(writeFixture(t, qqwrytest.Sample("2024年10月16日IP数据")))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return svc
+}
+
+func mustUint32(t testing.TB, addr netip.Addr) uint32 {
+    t.Helper()
+    v, err := qqwry.AddrToUint32(addr)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return v
+}
+
+// TestLookupVariants 确认三个查询入口返回相同的结果，仅 Lookup 填充 IP。
+func TestLookupVariants(t *testing.T) {
+    svc := newSampleService(t)
+    for _, ip := range benchIPs {
+        addr := netip.MustParseAddr(ip)
+        byString, err := svc.Lookup(ip)
+        if err != nil {
+            t.Fatalf("Lookup(%s): %v", ip, err)
+        }
+        if byString.IP != ip || byString.Addr != addr || byString.Country == "" {
+            t.Fatalf("Lookup(%s) = %+v", ip, byString)
+        }
+        byAddr, err := svc.LookupAddr(addr)
+        if err != nil {
+            t.Fatalf("LookupAddr(%s): %v", ip, err)
+        }
+        byUint32, err := svc.LookupUint32(mustUint32(t, addr))
+        if err != nil {
+            t.Fatalf("LookupUint32(%s): %v", ip, err)
+        }
+        byString.IP = ""
+        if byAddr != byString || byUint32 != byString {
+            t.Fatalf("%s: 查询入口结果不一致\nLookup:       %+v\nLookupAddr:   %+v\nLookupUint32: %+v", ip, byString, byAddr, byUint32)
+        }
+    }
+}
+
+// TestLookupAllocs 确认命中时各查询入口均不产生内存分配。
+func TestLookupAllocs(t *testing.T) {
+    svc := newSampleService(t)
+    for _, ip := range benchIPs {
+        addr := netip.MustParseAddr(ip)
+        value := mustUint32(t, addr)
+        cases := []struct {
+            name   string
+            lookup func() (Result, error)
+        }{
+            {"Lookup", func() (Result, error) { return svc.Lookup(ip) }},
+            {"LookupAddr", func() (Result, error) { return svc.LookupAddr(addr) }},
+            {"LookupUint32", func() (Result, error) { return svc.LookupUint32(value) }},
+        }
+        for _, c := range cases {
+            var lookupErr error
+            allocs := testing.AllocsPerRun(100, func() {
+                if _, err := c.lookup(); err != nil {
+                    lookupErr = err
+                }
+            })
+            if lookupErr != nil {
+                t.Fatalf("%s(%s): %v", c.name, ip, lookupErr)
+            }
+            if allocs != 0 {
+                t.Errorf("%s(%s) allocs = %v，期望 0", c.name, ip, allocs)
+            }
+        }
+    }
+}
+
+func BenchmarkLookup(b *testing.B) {
+    svc := newSampleService(b)
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := svc.Lookup(benchIPs[i%len(benchIPs)]); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkLookupAddr(b *testing.B) {
+    svc := newSampleService(b)
+    addrs := make([]netip.Addr, len(benchIPs))
+    for i, ip := range benchIPs {
+        addrs[i] = netip.MustParseAddr(ip)
+    }
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := svc.LookupAddr(addrs[i%len(addrs)]); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkLookupUint32(b *testing.B) {
+    svc := newSampleService(b)
+    values := make([]uint32, len(benchIPs))
+    for i, ip := range benchIPs {
+        values[i] = mustUint32(b, netip.MustParseAddr(ip))
+    }
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := svc.LookupUint32(values[i%len(values)]); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ipservice/docs"
+	"ipservice/internal/i18n"
+)
+
+// webFS 为内置的页面模板（web/templates）与静态文件（web/static）。
+//
+//go:embed web
+var webFS embed.FS
+
+// assetFS 将内置资源组织为统一的目录结构：templates/、static/ 来自 web 目录，docs/ 来自仓库的 docs 目录。
+type assetFS struct {
+	web  fs.FS
+	docs fs.FS
+}
+
+func (a assetFS) Open(name string) (fs.File, error) {
+	if rest, ok := strings.CutPrefix(name, "docs/"); ok {
+		return a.docs.Open(rest)
+	}
+	return a.web.Open(name)
+}
+
+// overlayFS 优先从 override 打开文件，不存在时回退到 base。
+type overlayFS struct {
+	override fs.FS
+	base     fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.override.Open(name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+	return o.base.Open(name)
+}
+
+// newAssets 返回页面资源的文件系统；dir 非空时其中的同名文件优先于内置版本，便于运维定制页面而无需重新编译。
+func newAssets(dir string) (fs.FS, error) {
+	web, err := fs.Sub(webFS, "web")
+	if err != nil {
+		return nil, err
+	}
+	var assets fs.FS = assetFS{web: web, docs: docs.FS}
+	if dir == "" {
+		return assets, nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("页面资源目录不可用: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("页面资源目录不可用: %s 不是目录", dir)
+	}
+	return overlayFS{override: os.DirFS(dir), base: assets}, nil
+}
+
+// 页面的内容安全策略：脚本与样式仅允许来自本站的静态文件，禁止内联脚本、内联样式与被其他站点嵌入。
+const pageCSP = "default-src 'self'; script-src 'self'; style-src 'self'; img-src 'self' data:; connect-src 'self'; " +
+	"object-src 'none'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'"
+
+// pageFuncs 为页面模板可用的函数：t 返回本地化文案，tlink 返回嵌入链接的本地化文案。
+var pageFuncs = template.FuncMap{
+	"t":     i18n.T,
+	"tlink": tlink,
+}
+
+// tlink 将文案中的 %s 依次替换为链接，links 按 href、文本成对给出；文案与链接均经过转义。
+func tlink(lang, key string, links ...string) (template.HTML, error) {
+	if len(links)%2 != 0 {
+		return "", fmt.Errorf("tlink %s: 链接参数须按 href、文本成对给出", key)
+	}
+	args := make([]any, 0, len(links)/2)
+	for i := 0; i < len(links); i += 2 {
+		args = append(args, `<a href="`+template.HTMLEscapeString(links[i])+`" target="_blank" rel="noopener noreferrer">`+
+			template.HTMLEscapeString(links[i+1])+`</a>`)
+	}
+	return template.HTML(fmt.Sprintf(template.HTMLEscapeString(i18n.T(lang, key)), args...)), nil
+}
+
+// parsePage 解析 templates/ 下的页面模板及共用的 layout.html；页面模板需定义 title 与 content，可选定义 scripts。
+func parsePage(assets fs.FS, name string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(pageFuncs).ParseFS(assets, "templates/layout.html", "templates/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("解析页面模板 %s 失败: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// executePage 以共用布局渲染页面。
+func executePage(tmpl *template.Template, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return nil, fmt.Errorf("渲染页面模板 %s 失败: %w", tmpl.Name(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeHTML 输出 HTML 页面并附带内容安全策略。
+func writeHTML(c *gin.Context, csp string, page []byte) {
+	c.Header("Content-Security-Policy", csp)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// serveStatic 返回 static/ 下文件的处理函数，仅输出普通文件，不提供目录列表。
+func serveStatic(assets fs.FS) gin.HandlerFunc {
+	static, _ := fs.Sub(assets, "static")
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(path.Clean(c.Param("filepath")), "/")
+		f, err := static.Open(name)
+		if err != nil {
+			respondError(c, http.StatusNotFound, codeRouteNotFound)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			respondError(c, http.StatusNotFound, codeRouteNotFound)
+			return
+		}
+		content, ok := f.(io.ReadSeeker)
+		if !ok {
+			data, err := io.ReadAll(f)
+			if err != nil {
+				writeError(c, err)
+				return
+			}
+			content = bytes.NewReader(data)
+		}
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			c.Header("Content-Type", ctype)
+		}
+		// 内置文件的修改时间为零值，此时 ServeContent 不输出 Last-Modified
+		http.ServeContent(c.Writer, c.Request, name, info.ModTime(), content)
+	}
+}
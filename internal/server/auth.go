@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextAPIKey 为认证通过的 *APIKey 在 gin.Context 中的键。
+const contextAPIKey = "auth.key"
+
+// Authenticator 校验请求携带的 API Key，并按接口所需的 scope 授权。
+type Authenticator struct {
+	store          *KeyStore
+	allowAnonymous bool
+}
+
+// NewAuthenticator 创建认证器；allowAnonymous 为 true 时，未携带密钥的请求仍可访问 lookup 范围的接口。
+func NewAuthenticator(store *KeyStore, allowAnonymous bool) *Authenticator {
+	return &Authenticator{store: store, allowAnonymous: allowAnonymous}
+}
+
+// Store 返回认证器使用的密钥库。
+func (a *Authenticator) Store() *KeyStore {
+	return a.store
+}
+
+// require 返回要求指定 scope 的中间件：缺少或无效的密钥返回 401，权限不足返回 403。
+func (a *Authenticator) require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plain := apiKeyFromRequest(c)
+		if plain == "" {
+			if scope == ScopeLookup && a.allowAnonymous {
+				c.Next()
+				return
+			}
+			abortWithError(c, http.StatusUnauthorized, codeMissingAPIKey)
+			return
+		}
+
+		key := a.store.Lookup(plain)
+		switch {
+		case key == nil || key.Disabled:
+			abortWithError(c, http.StatusUnauthorized, codeInvalidAPIKey)
+			return
+		case key.Expired(time.Now()):
+			abortWithError(c, http.StatusUnauthorized, codeExpiredAPIKey)
+			return
+		case !key.HasScope(scope):
+			abortWithError(c, http.StatusForbidden, codeInsufficientScope)
+			return
+		}
+
+		c.Set(contextAPIKey, key)
+		c.Set(contextClientKey, "key:"+key.ID)
+		c.Next()
+	}
+}
+
+// apiKeyFromRequest 依次读取 X-API-Key 请求头与 api_key 查询参数。
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key
+	}
+	return strings.TrimSpace(c.Query("api_key"))
+}
+
+// apiKeyFrom 返回认证中间件写入的密钥记录，匿名请求返回 nil。
+func apiKeyFrom(c *gin.Context) *APIKey {
+	if v, ok := c.Get(contextAPIKey); ok {
+		if key, ok := v.(*APIKey); ok {
+			return key
+		}
+	}
+	return nil
+}
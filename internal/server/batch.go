@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type batchRequest struct {
+	IPs []string `json:"ips" binding:"required"`
+}
+
+type batchItem struct {
+	IP     string      `json:"ip" xml:"ip"`
+	Result *ipResponse `json:"result,omitempty" xml:"result,omitempty"`
+	Code   string      `json:"code,omitempty" xml:"code,omitempty"`
+	Error  string      `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+type batchResponse struct {
+	XMLName xml.Name    `json:"-" xml:"batch"`
+	Total   int         `json:"total" xml:"total"`
+	Results []batchItem `json:"results" xml:"results>item"`
+}
+
+// text 每行输出一个 IP 及其归属信息，以制表符分隔；查询失败的行输出错误信息。
+func (r batchResponse) text() string {
+	lines := make([]string, 0, len(r.Results))
+	for _, item := range r.Results {
+		if item.Result != nil {
+			lines = append(lines, item.IP+"\t"+item.Result.text())
+		} else {
+			lines = append(lines, item.IP+"\terror: "+item.Error)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r batchResponse) csvRecords() [][]string {
+	records := make([][]string, 0, len(r.Results)+1)
+	records = append(records, append(append([]string(nil), ipCSVHeader...), "code", "error"))
+	for _, item := range r.Results {
+		var row []string
+		if item.Result != nil {
+			row = item.Result.csvRow()
+		} else {
+			row = make([]string, len(ipCSVHeader))
+			row[0] = item.IP
+		}
+		records = append(records, append(row, item.Code, item.Error))
+	}
+	return records
+}
+
+// queryBatch 批量查询多个 IP，单个 IP 查询失败不影响其他结果，数量上限由 Options.BatchLimit 决定。
+func (h *handler) queryBatch(c *gin.Context) {
+	var req batchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+	if len(req.IPs) > h.opts.BatchLimit {
+		respondError(c, http.StatusBadRequest, codeBatchTooLarge, h.opts.BatchLimit)
+		return
+	}
+
+	lang := displayLang(c)
+	resp := batchResponse{Total: len(req.IPs), Results: make([]batchItem, 0, len(req.IPs))}
+	for _, ip := range req.IPs {
+		item := batchItem{IP: ip}
+		if result, err := h.service.Lookup(ip); err != nil {
+			_, item.Code = classifyError(err)
+			item.Error = errorMessage(lang, item.Code)
+		} else {
+			r := newIPResponse(result, lang)
+			item.Result = &r
+		}
+		resp.Results = append(resp.Results, item)
+	}
+	respond(c, http.StatusOK, resp)
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCIDRLimit = 1000
+
+type cidrResponse struct {
+	CIDR      string          `json:"cidr"`
+	Total     int             `json:"total"`
+	Truncated bool            `json:"truncated"`
+	Records   []rangeResponse `json:"records"`
+}
+
+// queryByCIDR 返回与网段重叠的全部记录，例如 /cidr/1.2.0.0/16，可通过 limit 控制返回条数，上限由 Options.CIDRLimit 决定。
+func (h *handler) queryByCIDR(c *gin.Context) {
+	limit := defaultCIDRLimit
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	limit = min(limit, h.opts.CIDRLimit)
+
+	overlap, err := h.service.Overlapping(strings.TrimPrefix(c.Param("cidr"), "/"), limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	resp := cidrResponse{
+		CIDR:      overlap.Prefix,
+		Total:     len(overlap.Ranges),
+		Truncated: overlap.Truncated,
+		Records:   make([]rangeResponse, 0, len(overlap.Ranges)),
+	}
+	for _, r := range overlap.Ranges {
+		resp.Records = append(resp.Records, newRangeResponse(r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,451 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"ipservice/internal/ipdb"
+)
+
+// 兼容接口分组名称，用于 Options.Compat。各分组挂载在 /compat/<名称> 下，
+// 旧客户端只需将接口基地址指向该前缀即可沿用原有的请求与解析逻辑。
+const (
+	CompatIPAPI    = "ip-api"   // ip-api.com：/json/{ip}、POST /batch
+	CompatIPInfo   = "ipinfo"   // ipinfo.io：/{ip}/json、/{ip}/{field}
+	CompatTaobao   = "taobao"   // ip.taobao.com：/outGetIpInfo?ip=
+	CompatPconline = "pconline" // whois.pconline.com.cn：/ipJson.jsp?ip=
+)
+
+// registerCompatRoutes 按名称注册兼容接口分组，认证与限流沿用 lookup 权限范围；
+// ip-api 的批量接口与 /ip/batch 一致，要求 batch 权限。
+func registerCompatRoutes(router *gin.Engine, h *handler, names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		group := router.Group("/compat/"+name, h.opts.guard(ScopeLookup)...)
+		switch name {
+		case CompatIPAPI:
+			group.GET("/json", h.ipAPI)
+			group.GET("/json/:query", h.ipAPI)
+			router.Group("/compat/"+name, h.opts.guard(ScopeBatch)...).POST("/batch", h.ipAPIBatch)
+		case CompatIPInfo:
+			group.GET("", h.ipInfo)
+			group.GET("/:ip", h.ipInfo)
+			group.GET("/:ip/:field", h.ipInfo)
+		case CompatTaobao:
+			group.GET("/outGetIpInfo", h.taobao)
+			group.POST("/outGetIpInfo", h.taobao)
+			group.GET("/service/getIpInfo.php", h.taobao)
+		case CompatPconline:
+			group.GET("/ipJson.jsp", h.pconline)
+		default:
+			return fmt.Errorf("未知的兼容接口: %s", name)
+		}
+	}
+	return nil
+}
+
+// lookupOrClient 查询指定 IP，ip 为空时查询客户端来源 IP。
+func (h *handler) lookupOrClient(c *gin.Context, ip string) (ipdb.Result, error) {
+	if ip == "" {
+		client, err := extractClientIPv4(c)
+		if err != nil {
+			return ipdb.Result{IP: c.ClientIP()}, err
+		}
+		ip = client
+	}
+	result, err := h.service.Lookup(ip)
+	if err != nil {
+		result.IP = ip
+	}
+	return result, err
+}
+
+// ---- ip-api.com ----
+
+// ipAPIResponse 对应 ip-api.com 的 JSON 响应，qqwry 不包含的字段保持空值。
+type ipAPIResponse struct {
+	Status      string  `json:"status"`
+	Message     string  `json:"message,omitempty"`
+	Country     string  `json:"country,omitempty"`
+	CountryCode string  `json:"countryCode,omitempty"`
+	Region      string  `json:"region,omitempty"`
+	RegionName  string  `json:"regionName,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Zip         string  `json:"zip,omitempty"`
+	Lat         float64 `json:"lat,omitempty"`
+	Lon         float64 `json:"lon,omitempty"`
+	Timezone    string  `json:"timezone,omitempty"`
+	ISP         string  `json:"isp,omitempty"`
+	Org         string  `json:"org,omitempty"`
+	AS          string  `json:"as,omitempty"`
+	Query       string  `json:"query"`
+}
+
+// ipAPIBatchLimit 与 ip-api.com 的批量接口上限保持一致。
+const ipAPIBatchLimit = 100
+
+// ipAPI 兼容 ip-api.com 的 /json/{query}：失败时同样返回 200，以 status=fail 与 message 说明原因。
+func (h *handler) ipAPI(c *gin.Context) {
+	resp := h.ipAPILookup(c, c.Param("query"), c.Query("lang"))
+	c.JSON(http.StatusOK, filterFields(resp, c.Query("fields")))
+}
+
+// ipAPIBatch 兼容 ip-api.com 的 POST /batch，请求体为 IP 字符串或 {"query": ...} 对象组成的数组。
+func (h *handler) ipAPIBatch(c *gin.Context) {
+	var items []json.RawMessage
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid json"})
+		return
+	}
+	if len(items) > ipAPIBatchLimit {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "too many queries"})
+		return
+	}
+
+	results := make([]any, 0, len(items))
+	for _, raw := range items {
+		var query struct {
+			Query  string `json:"query"`
+			Fields string `json:"fields"`
+			Lang   string `json:"lang"`
+		}
+		if err := json.Unmarshal(raw, &query.Query); err != nil {
+			json.Unmarshal(raw, &query)
+		}
+		fields := query.Fields
+		if fields == "" {
+			fields = c.Query("fields")
+		}
+		lang := query.Lang
+		if lang == "" {
+			lang = c.Query("lang")
+		}
+		// 批量接口不存在“查询自身”的语义，空查询按非法输入处理
+		if query.Query == "" {
+			results = append(results, filterFields(ipAPIResponse{Status: "fail", Message: "invalid query"}, fields))
+			continue
+		}
+		results = append(results, filterFields(h.ipAPILookup(c, query.Query, lang), fields))
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *handler) ipAPILookup(c *gin.Context, query, lang string) ipAPIResponse {
+	result, err := h.lookupOrClient(c, strings.TrimSpace(query))
+	if err != nil {
+		return ipAPIResponse{Status: "fail", Message: "invalid query", Query: result.IP}
+	}
+	switch result.Scope {
+	case ipdb.ScopePublic:
+	case ipdb.ScopeMulticast, ipdb.ScopeReserved:
+		return ipAPIResponse{Status: "fail", Message: "reserved range", Query: result.IP}
+	default:
+		return ipAPIResponse{Status: "fail", Message: "private range", Query: result.IP}
+	}
+
+	country := result.CountryNameEN
+	if strings.HasPrefix(strings.ToLower(lang), "zh") || country == "" {
+		country = result.Country
+		if result.CountryNameZH != "" {
+			country = result.CountryNameZH
+		}
+	}
+	return ipAPIResponse{
+		Status:      "success",
+		Country:     country,
+		CountryCode: result.CountryCode,
+		RegionName:  result.Province,
+		City:        result.City,
+		Lat:         result.Latitude,
+		Lon:         result.Longitude,
+		ISP:         result.Area,
+		Org:         result.Area,
+		Query:       result.IP,
+	}
+}
+
+// filterFields 按 ip-api.com 的 fields 参数（逗号分隔的字段名）裁剪响应，未指定时原样返回。
+func filterFields(v any, fields string) any {
+	if strings.TrimSpace(fields) == "" {
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return v
+	}
+	filtered := make(map[string]any)
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if value, ok := all[name]; ok {
+			filtered[name] = value
+		}
+	}
+	// ip-api.com 在失败时始终返回 status 与 message
+	if all["status"] == "fail" {
+		filtered["status"] = all["status"]
+		filtered["message"] = all["message"]
+	}
+	return filtered
+}
+
+// ---- ipinfo.io ----
+
+// ipInfoResponse 对应 ipinfo.io 的 JSON 响应，Org 使用 qqwry 的区域（运营商）字段。
+type ipInfoResponse struct {
+	IP      string `json:"ip"`
+	Bogon   bool   `json:"bogon,omitempty"`
+	City    string `json:"city,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Country string `json:"country,omitempty"`
+	Loc     string `json:"loc,omitempty"`
+	Org     string `json:"org,omitempty"`
+}
+
+type ipInfoError struct {
+	Status int `json:"status"`
+	Error  struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ipInfoNotFound 以 ipinfo.io 的错误结构返回 404。
+func ipInfoNotFound(c *gin.Context, title, message string) {
+	var resp ipInfoError
+	resp.Status = http.StatusNotFound
+	resp.Error.Title = title
+	resp.Error.Message = message
+	c.JSON(http.StatusNotFound, resp)
+}
+
+// ipInfo 兼容 ipinfo.io：/ 与 /json 查询客户端自身，/{ip} 与 /{ip}/json 返回 JSON，
+// /{ip}/{field} 以纯文本返回单个字段。
+func (h *handler) ipInfo(c *gin.Context) {
+	ip, field := c.Param("ip"), c.Param("field")
+	if ip == "json" {
+		ip = ""
+	}
+
+	result, err := h.lookupOrClient(c, ip)
+	if err != nil {
+		ipInfoNotFound(c, "Wrong ip", "Please provide a valid IP address")
+		return
+	}
+
+	resp := ipInfoResponse{IP: result.IP}
+	if result.Scope != ipdb.ScopePublic {
+		resp.Bogon = true
+	} else {
+		resp.City = result.City
+		resp.Region = result.Province
+		resp.Country = result.CountryCode
+		resp.Org = result.Area
+		if result.Latitude != 0 || result.Longitude != 0 {
+			resp.Loc = strconv.FormatFloat(result.Latitude, 'f', 4, 64) + "," + strconv.FormatFloat(result.Longitude, 'f', 4, 64)
+		}
+	}
+
+	if field == "" || field == "json" {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	values := map[string]string{
+		"ip":      resp.IP,
+		"city":    resp.City,
+		"region":  resp.Region,
+		"country": resp.Country,
+		"loc":     resp.Loc,
+		"org":     resp.Org,
+	}
+	value, ok := values[field]
+	if !ok {
+		ipInfoNotFound(c, "Wrong field", "Please provide a valid field name")
+		return
+	}
+	if value == "" {
+		value = "undefined"
+	}
+	c.String(http.StatusOK, "%s\n", value)
+}
+
+// ---- ip.taobao.com ----
+
+// taobaoData 对应淘宝 IP 接口 data 字段，未知的名称与编号分别以 XX、xx 表示。
+type taobaoData struct {
+	IP        string `json:"ip"`
+	QueryIP   string `json:"queryIp"`
+	Country   string `json:"country"`
+	CountryID string `json:"country_id"`
+	Area      string `json:"area"`
+	AreaID    string `json:"area_id"`
+	Region    string `json:"region"`
+	RegionID  string `json:"region_id"`
+	City      string `json:"city"`
+	CityID    string `json:"city_id"`
+	County    string `json:"county"`
+	CountyID  string `json:"county_id"`
+	ISP       string `json:"isp"`
+	ISPID     string `json:"isp_id"`
+}
+
+type taobaoResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data any    `json:"data"`
+}
+
+// taobao 兼容淘宝 IP 接口：code 为 0 表示成功，失败时 code 为 1 且 data 为空对象，HTTP 状态码始终为 200。
+// ip 参数为 myip 时查询客户端自身。
+func (h *handler) taobao(c *gin.Context) {
+	ip := c.Query("ip")
+	if ip == "" {
+		ip = c.PostForm("ip")
+	}
+	if ip == "" {
+		c.JSON(http.StatusOK, taobaoResponse{Code: 1, Msg: "ip is empty", Data: gin.H{}})
+		return
+	}
+	if ip == "myip" {
+		ip = ""
+	}
+
+	result, err := h.lookupOrClient(c, ip)
+	if err != nil {
+		c.JSON(http.StatusOK, taobaoResponse{Code: 1, Msg: "invalid ip", Data: gin.H{}})
+		return
+	}
+
+	data := taobaoData{
+		IP:        result.IP,
+		QueryIP:   result.IP,
+		Country:   orUnknown(result.CountryNameZH, "XX"),
+		CountryID: orUnknown(result.CountryCode, "xx"),
+		Region:    orUnknown(shortRegionName(result.Province), "XX"),
+		RegionID:  orUnknown(provinceCode(result.Adcode), "xx"),
+		City:      orUnknown(shortRegionName(result.City), "XX"),
+		CityID:    "xx",
+		ISP:       orUnknown(result.Area, "XX"),
+		ISPID:     "xx",
+		AreaID:    "xx",
+		CountyID:  "xx",
+	}
+	if result.City != "" {
+		data.CityID = result.Adcode
+	}
+	if result.Scope != ipdb.ScopePublic {
+		data.Country = "内网IP"
+		data.CountryID = "xx"
+	}
+	c.JSON(http.StatusOK, taobaoResponse{Code: 0, Msg: "query success", Data: data})
+}
+
+// ---- whois.pconline.com.cn ----
+
+// pconlineResponse 对应太平洋网络 IP 接口的响应，err 为 noprovince、nocity 时表示对应区划未识别。
+type pconlineResponse struct {
+	IP          string `json:"ip"`
+	Pro         string `json:"pro"`
+	ProCode     string `json:"proCode"`
+	City        string `json:"city"`
+	CityCode    string `json:"cityCode"`
+	Region      string `json:"region"`
+	RegionCode  string `json:"regionCode"`
+	Addr        string `json:"addr"`
+	RegionNames string `json:"regionNames"`
+	Err         string `json:"err"`
+}
+
+// pconline 兼容太平洋网络 IP 接口：响应以 GBK 编码；json=true 时返回 JSON，
+// 携带 callback 时返回 callback({...});，否则返回 IPCallBack 脚本。
+func (h *handler) pconline(c *gin.Context) {
+	callback := c.Query("callback")
+	if callback != "" && (!jsonpCallback.MatchString(callback) || len(callback) > 128) {
+		abortWithError(c, http.StatusBadRequest, codeInvalidCallback)
+		return
+	}
+
+	resp := pconlineResponse{ProCode: "999999", CityCode: "0", RegionCode: "0"}
+	result, err := h.lookupOrClient(c, strings.TrimSpace(c.Query("ip")))
+	resp.IP = result.IP
+	switch {
+	case err != nil:
+		resp.Err = "noip"
+	default:
+		resp.Addr = strings.TrimSpace(result.Country + " " + result.Area)
+		resp.Pro = result.Province
+		resp.City = result.City
+		if result.Province == "" {
+			resp.Err = "noprovince"
+		} else {
+			resp.ProCode = provinceCode(result.Adcode)
+			if result.City == "" {
+				resp.Err = "nocity"
+			} else {
+				resp.CityCode = result.Adcode
+			}
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	body, contentType := string(data), "application/json; charset=GBK"
+	switch {
+	case callback != "":
+		body, contentType = callback+"("+body+");", "text/javascript; charset=GBK"
+	case c.Query("json") != "true":
+		body, contentType = "if(window.IPCallBack) {IPCallBack("+body+");}", "text/javascript; charset=GBK"
+	}
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(body)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType, []byte(encoded))
+}
+
+// ---- 公共辅助 ----
+
+func orUnknown(value, unknown string) string {
+	if value == "" {
+		return unknown
+	}
+	return value
+}
+
+// provinceCode 由区划代码推导省级代码，如 320500 → 320000。
+func provinceCode(adcode string) string {
+	if len(adcode) != 6 {
+		return ""
+	}
+	return adcode[:2] + "0000"
+}
+
+// regionSuffixes 为区划全称的常见后缀，按长度降序排列以优先匹配较长后缀。
+var regionSuffixes = []string{"维吾尔自治区", "壮族自治区", "回族自治区", "特别行政区", "自治区", "自治州", "地区", "省", "市"}
+
+// shortRegionName 将区划全称转换为简称，如 江苏省 → 江苏、广西壮族自治区 → 广西，与淘宝接口的写法一致。
+func shortRegionName(name string) string {
+	for _, suffix := range regionSuffixes {
+		if short, ok := strings.CutSuffix(name, suffix); ok && short != "" {
+			return short
+		}
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCompatBatchRequiresBatchScope 确认 ip-api 兼容的批量接口与 /ip/batch 一样要求 batch 权限，
+// 仅有 lookup 权限的密钥与匿名请求不能借此绕过。
+func TestCompatBatchRequiresBatchScope(t *testing.T) {
+	store, err := LoadKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookupKey, err := store.Add(APIKey{ID: "lookup-only", Scopes: []string{ScopeLookup}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batchKey, err := store.Add(APIKey{ID: "batch", Scopes: []string{ScopeLookup, ScopeBatch}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(t, Options{Auth: NewAuthenticator(store, true), Compat: []string{CompatIPAPI}})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		key    string
+		status int
+	}{
+		{"lookup 密钥查询单个 IP", http.MethodGet, "/compat/ip-api/json/1.2.3.4", lookupKey, http.StatusOK},
+		{"lookup 密钥批量查询", http.MethodPost, "/compat/ip-api/batch", lookupKey, http.StatusForbidden},
+		{"匿名批量查询", http.MethodPost, "/compat/ip-api/batch", "", http.StatusUnauthorized},
+		{"batch 密钥批量查询", http.MethodPost, "/compat/ip-api/batch", batchKey, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`["1.2.3.4","8.8.8.8"]`))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d，期望 %d，body = %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
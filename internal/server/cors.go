@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSOptions 为跨域访问配置，AllowOrigins 中的 "*" 表示任意来源，
+// "https://*.example.com" 形式的条目匹配该域名的任意子域。
+type CORSOptions struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+
+	// JSONP 为 true 时，GET 请求携带 callback 参数即以 JSONP 形式返回，供无法使用 CORS 的旧页面调用
+	JSONP bool
+}
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	defaultCORSHeaders = []string{"Content-Type", "X-API-Key"}
+	defaultCORSExpose  = []string{
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+		"X-RateLimit-Quota-Limit", "X-RateLimit-Quota-Remaining", "X-RateLimit-Quota-Reset",
+		"Retry-After", "X-Request-ID",
+	}
+)
+
+// cors 返回跨域中间件：预检请求直接应答，实际请求按来源补充响应头。
+// 需注册为全局中间件，使未注册 OPTIONS 路由的接口也能完成预检。
+func cors(opts CORSOptions) gin.HandlerFunc {
+	methods := strings.Join(orDefault(opts.AllowMethods, defaultCORSMethods), ", ")
+	headers := strings.Join(orDefault(opts.AllowHeaders, defaultCORSHeaders), ", ")
+	expose := strings.Join(orDefault(opts.ExposeHeaders, defaultCORSExpose), ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !originAllowed(opts.AllowOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowsAny(opts.AllowOrigins) && !opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			if opts.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if expose != "" {
+			c.Header("Access-Control-Expose-Headers", expose)
+		}
+		c.Next()
+	}
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+		// https://*.example.com 匹配任意子域，但不匹配 example.com 本身
+		if scheme, host, ok := strings.Cut(pattern, "://*."); ok {
+			prefix := scheme + "://"
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(host)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func allowsAny(allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(values, def []string) []string {
+	if len(values) == 0 {
+		return def
+	}
+	return values
+}
+
+// jsonpCallback 限定回调名为 JavaScript 标识符（可含点号），防止注入任意脚本。
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// jsonp 返回 JSONP 中间件：GET 请求携带合法的 callback 参数时，将 JSON 响应包装为 callback(...) 脚本。
+// 脚本标签无法读取状态码，因此 JSONP 响应统一返回 200，错误信息保留在响应体中。
+func jsonp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		callback := c.Query("callback")
+		if c.Request.Method != http.MethodGet || callback == "" {
+			c.Next()
+			return
+		}
+		if !jsonpCallback.MatchString(callback) || len(callback) > 128 {
+			abortWithError(c, http.StatusBadRequest, codeInvalidCallback)
+			return
+		}
+
+		buf := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = buf
+		c.Next()
+		c.Writer = buf.ResponseWriter
+
+		header := c.Writer.Header()
+		contentType := header.Get("Content-Type")
+		if !strings.HasPrefix(contentType, "application/json") && !strings.HasPrefix(contentType, "application/problem+json") {
+			c.Writer.WriteHeader(buf.status)
+			c.Writer.Write(buf.body.Bytes())
+			return
+		}
+		header.Set("Content-Type", "application/javascript; charset=utf-8")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Del("Content-Length")
+		c.Writer.WriteHeader(http.StatusOK)
+		// 前置注释可避免回调名被解析为其他语法（Rosetta Flash 等攻击的常见缓解方式）
+		c.Writer.WriteString("/**/" + callback + "(")
+		c.Writer.Write(bytes.TrimRight(buf.body.Bytes(), "\n"))
+		c.Writer.WriteString(");")
+	}
+}
+
+// bufferedWriter 暂存处理器写出的状态码与响应体，便于 JSONP 统一包装。
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
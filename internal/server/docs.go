@@ -1,120 +1,189 @@
 package server
 
 import (
+    "fmt"
+    "html/template"
+    "io/fs"
+    "log"
     "net/http"
-    "os"
+    "regexp"
     "strings"
 
     "github.com/gin-gonic/gin"
     blackfriday "github.com/russross/blackfriday/v2"
+
+    "ipservice/internal/i18n"
+    "ipservice/internal/ipdb"
 )
 
-func registerDocRoutes(router *gin.Engine) {
+// docsTitle 为 /docs 页面的标题。
+const docsTitle = "IP 服务 · API 使用说明"
+
+// registerDocRoutes 注册首页查询界面、文档页面与静态资源；模板在启动时解析，Markdown 文档与接口文档页面在启动时渲染并缓存，
+// 资源缺失或模板有误时返回错误。首页的脚本、样式与地图均来自内置资源，无需访问外网。
+func registerDocRoutes(router *gin.Engine, assets fs.FS, opts Options) error {
+    index, err := parsePage(assets, "index.html")
+    if err != nil {
+        return err
+    }
+    md, err := fs.ReadFile(assets, "docs/api_usage.md")
+    if err != nil {
+        return fmt.Errorf("读取文档失败: %w", err)
+    }
+    docsPage, err := renderMarkdown(assets, docsTitle, md)
+    if err != nil {
+        return err
+    }
+    openapiPage, err := fs.ReadFile(assets, "templates/openapi.html")
+    if err != nil {
+        return fmt.Errorf("读取接口文档页面失败: %w", err)
+    }
+
+    regionMap := newRegionMap(ipdb.Regions())
     router.GET("/", func(c *gin.Context) {
-        // 动态文档：根据当前 URL 生成可点击链接与 curl 示例
-        renderDynamicDocs(c)
+        // 查询界面，开发者区域根据当前 URL 生成可点击链接与 curl 示例
+        renderDynamicDocs(c, index, docsPageData{BatchLimit: opts.BatchLimit, Map: regionMap})
     })
 
     router.GET("/docs", func(c *gin.Context) {
-        md, err := os.ReadFile("docs/api_usage.md")
-        if err != nil {
-            c.String(http.StatusInternalServerError, "读取文档失败: %v", err)
-            return
-        }
-        renderMarkdown(c, "IP 服务 · API 使用说明", md)
+        writeHTML(c, pageCSP, docsPage)
+    })
+
+    // OpenAPI 文档及其浏览页面，页面脚本 static/openapi.js 读取 /openapi.json 渲染
+    router.GET("/openapi.json", serveOpenAPI)
+    router.GET("/openapi", func(c *gin.Context) {
+        writeHTML(c, pageCSP, openapiPage)
     })
 
+    router.GET("/static/*filepath", serveStatic(assets))
+    return nil
 }
 
-func renderMarkdown(c *gin.Context, title string, md []byte) {
-    html := blackfriday.Run(md)
-    page := []byte(`<!doctype html>
-<html lang="zh-CN">
-<head>
-<meta charset="utf-8" />
-<meta name="viewport" content="width=device-width, initial-scale=1" />
-<title>` + title + `</title>
-<style>
-body{font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, 'Noto Sans', 'PingFang SC', 'Hiragino Sans GB', 'Microsoft YaHei', sans-serif;line-height:1.6;margin:0;padding:32px;color:#222;background:#fff}
-main{max-width: 860px; margin: 0 auto}
-h1,h2,h3{line-height:1.25}
-code, pre{background:#f6f8fa;border-radius:6px}
-pre{padding:12px; overflow:auto}
-code{padding:2px 4px}
-a{color:#0969da; text-decoration:none}
-a:hover{text-decoration:underline}
-hr{border:none;border-top:1px solid #eee;margin:24px 0}
-</style>
-</head>
-<body>
-<main>` + string(html) + `<hr/><p>IP地址位置数据由<a href="https://www.cz88.net" target="_blank" rel="noopener noreferrer">纯真CZ88</a>提供支持</p></main>
-</body>
-</html>`)
-    c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+// renderMarkdown 将 Markdown 文档渲染为完整的 HTML 页面。
+func renderMarkdown(assets fs.FS, title string, md []byte) ([]byte, error) {
+    tmpl, err := parsePage(assets, "markdown.html")
+    if err != nil {
+        return nil, err
+    }
+    return executePage(tmpl, struct {
+        Lang    string
+        Title   string
+        Content template.HTML
+    }{
+        Lang:  i18n.ZH,
+        Title: title,
+        // 文档随二进制发布或由运维提供，内容可信，按原样输出 HTML
+        Content: template.HTML(blackfriday.Run(md)),
+    })
 }
 
+// validHost 限定转发头与 Host 中的主机名：域名、IPv4 或方括号包裹的 IPv6，可带端口。
+var validHost = regexp.MustCompile(`^(?:[A-Za-z0-9](?:[A-Za-z0-9.-]*[A-Za-z0-9])?|\[[0-9A-Fa-f:.]+\])(?::[0-9]{1,5})?$`)
+
 func baseURL(c *gin.Context) string {
-    // 优先读取反向代理头，其次回退到请求信息
+    // 优先读取反向代理头，其次回退到请求信息；取值不合法时忽略，避免伪造的请求头被写入页面
     scheme := "http"
-    if p := c.GetHeader("X-Forwarded-Proto"); p != "" {
-        scheme = strings.TrimSpace(strings.Split(p, ",")[0])
-    } else if c.Request.TLS != nil {
+    if c.Request.TLS != nil {
         scheme = "https"
     }
+    if p := c.GetHeader("X-Forwarded-Proto"); p != "" {
+        p = strings.ToLower(strings.TrimSpace(strings.Split(p, ",")[0]))
+        if p == "http" || p == "https" {
+            scheme = p
+        }
+    }
 
-    host := c.GetHeader("X-Forwarded-Host")
-    if host == "" {
+    host := strings.TrimSpace(strings.Split(c.GetHeader("X-Forwarded-Host"), ",")[0])
+    if !validHost.MatchString(host) {
         host = c.Request.Host
-    } else {
-        host = strings.TrimSpace(strings.Split(host, ",")[0])
     }
-    if host == "" {
+    if !validHost.MatchString(host) {
         host = "localhost"
     }
     return scheme + "://" + host
 }
 
-func renderDynamicDocs(c *gin.Context) {
-    base := baseURL(c)
-    exampleIP := "8.8.8.8"
-
-    // 动态 HTML：展示可点击链接、curl 示例与在线试用
-    html := "<!doctype html>" +
-        "<html lang=\"zh-CN\"><head><meta charset=\"utf-8\" />" +
-        "<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\" />" +
-        "<title>IP 服务 · 动态文档</title>" +
-        "<style>body{font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,'Helvetica Neue',Arial,'Noto Sans','PingFang SC','Hiragino Sans GB','Microsoft YaHei',sans-serif;line-height:1.6;margin:0;padding:32px;color:#222}main{max-width:860px;margin:0 auto}code,pre{background:#f6f8fa;border-radius:6px}pre{padding:12px;overflow:auto}code{padding:2px 4px}a{color:#0969da;text-decoration:none}a:hover{text-decoration:underline}input,button{font-size:14px;padding:6px 10px;margin:2px}label{display:block;margin-top:12px}hr{border:none;border-top:1px solid #eee;margin:24px 0}</style>" +
-        "</head><body><main>" +
-        "<h1>IP 服务 · 动态文档</h1>" +
-        "<p>当前基准地址：<code>" + base + "</code></p>" +
-
-        "<h2>快速链接</h2><ul>" +
-        "<li><a href='" + base + "/health' target='_blank'>GET /health</a></li>" +
-        "<li><a href='" + base + "/ip' target='_blank'>GET /ip</a></li>" +
-        "<li><a href='" + base + "/ip/" + exampleIP + "' target='_blank'>GET /ip/" + exampleIP + "</a></li>" +
-        "</ul>" +
-
-        "<h2>curl 示例</h2><pre><code>curl -s \"" + base + "/health\"\n" +
-        "curl -s \"" + base + "/ip\"\n" +
-        "curl -s \"" + base + "/ip/" + exampleIP + "\"\n" +
-        "curl -s -H 'Content-Type: application/json' -d '{\"ip\":\"" + exampleIP + "\"}' \"" + base + "/ip\"" +
-        "</code></pre>" +
-
-        "<h2>在线试用</h2>" +
-        "<div><label>目标 IPv4： <input id='ip' placeholder='例如 8.8.8.8' value='" + exampleIP + "'/></label>" +
-        "<button onclick=\"tryGet()\">GET /ip/{ip}</button>" +
-        "<button onclick=\"tryPost()\">POST /ip</button></div>" +
-        "<pre id='out' style='min-height:120px'></pre>" +
-
-        "<script>const base='" + base + "';\n" +
-        "function show(o){document.getElementById('out').textContent=typeof o==='string'?o:JSON.stringify(o,null,2)}\n" +
-        "async function tryGet(){const ip=document.getElementById('ip').value.trim(); if(!ip){show('请输入 IP');return;} try{const r=await fetch(base+'/ip/'+encodeURIComponent(ip)); show(await r.json());}catch(e){show(String(e))}}\n" +
-        "async function tryPost(){const ip=document.getElementById('ip').value.trim(); if(!ip){show('请输入 IP');return;} try{const r=await fetch(base+'/ip',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({ip})}); show(await r.json());}catch(e){show(String(e))}}\n" +
-        "</script>" +
-
-        "<hr/><p>更多静态说明请见 <a href='" + base + "/docs' target='_blank'>/docs</a>。</p>" +
-        "<p>IP地址位置数据由<a href='https://www.cz88.net' target='_blank' rel='noopener noreferrer'>纯真CZ88</a>提供支持</p>" +
-        "</main></body></html>"
-
-    c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+// docsPageData 为首页的模板数据。
+type docsPageData struct {
+    Lang       string
+    Other      string // 语言切换链接指向的语言
+    Base       string
+    ExampleIP  string
+    BatchLimit int
+    Map        regionMap
+    Messages   map[string]string // 页面脚本使用的文案
+}
+
+// scriptMessages 为首页脚本需要的文案标识。
+var scriptMessages = []string{
+    "docs.enter_ip", "docs.loading", "docs.meta", "docs.no_coordinates", "docs.history_empty",
+    "field.ip", "field.location", "field.scope", "field.country_name", "field.country_code", "field.continent",
+    "field.province", "field.city", "field.adcode", "field.coordinates", "field.range", "field.cidrs", "field.error",
+}
+
+func renderDynamicDocs(c *gin.Context, tmpl *template.Template, data docsPageData) {
+    lang := displayLang(c)
+    // 页面内的语言切换链接与查询请求均显式携带 lang 参数
+    other := i18n.EN
+    if lang == i18n.EN {
+        other = i18n.ZH
+    }
+    messages := make(map[string]string, len(scriptMessages))
+    for _, key := range scriptMessages {
+        messages[key] = i18n.T(lang, key)
+    }
+
+    data.Lang = lang
+    data.Other = other
+    data.Base = baseURL(c)
+    data.ExampleIP = "8.8.8.8"
+    data.Messages = messages
+    page, err := executePage(tmpl, data)
+    if err != nil {
+        log.Printf("请求 %s 渲染文档页失败: %v", c.GetString(contextRequestID), err)
+        respondError(c, http.StatusInternalServerError, codeInternal)
+        return
+    }
+    writeHTML(c, pageCSP, page)
+}
+
+// regionMap 为首页地图的绘制参数：将内置行政区划的中心点按经纬度等距投影到 SVG 坐标，
+// 页面脚本按相同参数换算查询结果的标注位置。
+type regionMap struct {
+    West, North float64 // 左上角经纬度
+    Scale       float64 // 每度对应的 SVG 单位
+    Width       int
+    Height      int
+    Points      []mapPoint
+}
+
+type mapPoint struct {
+    X, Y float64
+}
+
+// 地图范围覆盖中国全境
+const (
+    mapWest  = 73.0
+    mapEast  = 136.0
+    mapNorth = 54.0
+    mapSouth = 16.0
+    mapScale = 10.0
+)
+
+func newRegionMap(regions []ipdb.Region) regionMap {
+    m := regionMap{
+        West:   mapWest,
+        North:  mapNorth,
+        Scale:  mapScale,
+        Width:  int((mapEast - mapWest) * mapScale),
+        Height: int((mapNorth - mapSouth) * mapScale),
+        Points: make([]mapPoint, 0, len(regions)),
+    }
+    for _, r := range regions {
+        m.Points = append(m.Points, mapPoint{
+            X: (r.Longitude - mapWest) * mapScale,
+            Y: (mapNorth - r.Latitude) * mapScale,
+        })
+    }
+    return m
 }
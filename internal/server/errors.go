@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"ipservice/internal/i18n"
+	"ipservice/internal/ipdb"
+)
+
+// 错误码，对应错误响应中的 code 字段，取值保持稳定，供客户端按机器可读的值分支处理。
+const (
+	codeInvalidIP        = "invalid_ip"
+	codeIPv6NotSupported = "ipv6_not_supported"
+	codeNotFound         = "not_found"
+	codeDecodeCountry    = "decode_country_failed"
+	codeDecodeArea       = "decode_area_failed"
+	codeInvalidQuery     = "invalid_query"
+	codeInvalidCIDR      = "invalid_cidr"
+	codeCorruptData      = "corrupt_data"
+	codeInternal         = "internal_error"
+
+	codeInvalidJSON     = "invalid_json"
+	codeInvalidRequest  = "invalid_request"
+	codeBatchTooLarge   = "batch_too_large"
+	codeClientIP        = "client_ip_unavailable"
+	codeClientIPv6      = "client_ipv6"
+	codeInvalidFormat   = "invalid_format"
+	codeInvalidCallback = "invalid_callback"
+	codeRouteNotFound   = "route_not_found"
+	codeReloadFailed    = "reload_failed"
+
+	codeMissingAPIKey     = "missing_api_key"
+	codeInvalidAPIKey     = "invalid_api_key"
+	codeExpiredAPIKey     = "expired_api_key"
+	codeInsufficientScope = "insufficient_scope"
+	codeRateLimited       = "rate_limited"
+	codeQuotaExceeded     = "quota_exceeded"
+)
+
+// problem 为统一的错误响应，遵循 RFC 7807（application/problem+json）。
+// type 固定为 about:blank，title 为 HTTP 状态描述；code、message、request_id 为扩展字段，
+// message 与 detail 内容相同（按请求语言本地化），便于未按 RFC 7807 解析的客户端读取。
+type problem struct {
+	XMLName   xml.Name `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+	Type      string   `json:"type" xml:"type"`
+	Title     string   `json:"title" xml:"title"`
+	Status    int      `json:"status" xml:"status"`
+	Detail    string   `json:"detail" xml:"detail"`
+	Instance  string   `json:"instance,omitempty" xml:"instance,omitempty"`
+	Code      string   `json:"code" xml:"code"`
+	Message   string   `json:"message" xml:"message"`
+	RequestID string   `json:"request_id,omitempty" xml:"request_id,omitempty"`
+}
+
+func newProblem(c *gin.Context, status int, code string, args ...any) problem {
+	message := errorMessage(displayLang(c), code, args...)
+	return problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    message,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(contextRequestID),
+	}
+}
+
+func (p problem) text() string {
+	return "error: " + p.Message + " (" + p.Code + ")"
+}
+
+func (p problem) csvRecords() [][]string {
+	return [][]string{{"status", "code", "message", "request_id"}, {fmt.Sprint(p.Status), p.Code, p.Message, p.RequestID}}
+}
+
+// errorMessage 返回错误码在指定语言下的提示文案。
+func errorMessage(lang, code string, args ...any) string {
+	return i18n.T(lang, "error."+code, args...)
+}
+
+// respondError 以协商后的格式输出错误响应。
+func respondError(c *gin.Context, status int, code string, args ...any) {
+	respond(c, status, newProblem(c, status, code, args...))
+}
+
+// abortWithError 输出错误响应并终止后续处理，供中间件使用。
+func abortWithError(c *gin.Context, status int, code string, args ...any) {
+	respondError(c, status, code, args...)
+	c.Abort()
+}
+
+// writeError 将领域错误映射为 HTTP 状态码与错误码；未识别的内部错误仅记录日志，不向客户端透出细节。
+func writeError(c *gin.Context, err error) {
+	status, code := classifyError(err)
+	if code == codeInternal || code == codeCorruptData {
+		log.Printf("请求 %s 处理失败: %v", c.GetString(contextRequestID), err)
+	}
+	respondError(c, status, code)
+}
+
+// classifyError 根据 ipdb 的哨兵错误确定 HTTP 状态码与错误码。
+func classifyError(err error) (int, string) {
+	switch {
+	case errors.Is(err, ipdb.ErrInvalidIP):
+		return http.StatusBadRequest, codeInvalidIP
+	case errors.Is(err, ipdb.ErrIPv6NotSupported):
+		return http.StatusBadRequest, codeIPv6NotSupported
+	case errors.Is(err, ipdb.ErrNotFound):
+		return http.StatusNotFound, codeNotFound
+	case errors.Is(err, ipdb.ErrDecodeCountry):
+		return http.StatusBadRequest, codeDecodeCountry
+	case errors.Is(err, ipdb.ErrDecodeArea):
+		return http.StatusBadRequest, codeDecodeArea
+	case errors.Is(err, ipdb.ErrInvalidQuery):
+		return http.StatusBadRequest, codeInvalidQuery
+	case errors.Is(err, ipdb.ErrInvalidCIDR):
+		return http.StatusBadRequest, codeInvalidCIDR
+	case errors.Is(err, ipdb.ErrCorruptData):
+		return http.StatusInternalServerError, codeCorruptData
+	default:
+		return http.StatusInternalServerError, codeInternal
+	}
+}
+
+// writeBindError 输出请求体绑定失败的错误，仅透出字段名，不返回校验器的原始报错。
+func writeBindError(c *gin.Context, err error) {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		respondError(c, http.StatusBadRequest, codeInvalidJSON)
+		return
+	}
+	fields := make([]string, 0, len(invalid))
+	for _, fe := range invalid {
+		// 字段存在但不是合法 IP 时与查询接口的错误保持一致
+		if fe.Tag() == "ip" && len(invalid) == 1 {
+			respondError(c, http.StatusBadRequest, codeInvalidIP)
+			return
+		}
+		fields = append(fields, strings.ToLower(fe.Field()))
+	}
+	respondError(c, http.StatusBadRequest, codeInvalidRequest, strings.Join(fields, ", "))
+}
+
+// writeClientIPError 输出无法识别客户端 IPv4 时的错误。
+func writeClientIPError(c *gin.Context, err error) {
+	var v6 *clientIPv6Error
+	if errors.As(err, &v6) {
+		respondError(c, http.StatusBadRequest, codeClientIPv6, v6.ip)
+		return
+	}
+	respondError(c, http.StatusBadRequest, codeClientIP)
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API Key 的权限范围
+const (
+	ScopeLookup = "lookup" // 单个 IP、网段查询与反向检索
+	ScopeBatch  = "batch"  // 批量查询
+	ScopeAdmin  = "admin"  // 管理接口，如热加载数据与密钥
+)
+
+const apiKeyPrefix = "ipk_"
+
+// APIKey 为密钥库中的一条记录，仅保存密钥的 SHA-256 摘要。
+type APIKey struct {
+	ID        string     `json:"id"`
+	Hash      string     `json:"hash"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Disabled  bool       `json:"disabled,omitempty"`
+
+	// 以下为专属限流参数，零值时沿用全局配置
+	Rate       float64 `json:"rate,omitempty"`
+	Burst      int     `json:"burst,omitempty"`
+	DailyQuota int64   `json:"daily_quota,omitempty"`
+}
+
+// HasScope 判断密钥是否具备指定权限，admin 隐含全部权限。
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 判断密钥在 now 时刻是否已过期。
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+type keyFile struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// KeyStore 为基于本地 JSON 文件的 API Key 库，支持热加载。
+type KeyStore struct {
+	path string
+
+	mu     sync.RWMutex
+	keys   []APIKey
+	byHash map[string]*APIKey
+}
+
+// LoadKeyStore 读取密钥库文件，文件不存在时返回空库，便于随后通过 Add 创建。
+func LoadKeyStore(path string) (*KeyStore, error) {
+	s := &KeyStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload 重新读取密钥库文件。
+func (s *KeyStore) Reload() error {
+	var f keyFile
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("读取密钥库失败: %w", err)
+	default:
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("密钥库格式不合法: %w", err)
+		}
+	}
+
+	byHash := make(map[string]*APIKey, len(f.Keys))
+	ids := make(map[string]bool, len(f.Keys))
+	for i := range f.Keys {
+		k := &f.Keys[i]
+		if err := validateKey(k); err != nil {
+			return fmt.Errorf("密钥库第 %d 条记录: %w", i+1, err)
+		}
+		if ids[k.ID] {
+			return fmt.Errorf("密钥库中存在重复的 id: %s", k.ID)
+		}
+		ids[k.ID] = true
+		k.Hash = strings.ToLower(k.Hash)
+		byHash[k.Hash] = k
+	}
+
+	s.mu.Lock()
+	s.keys = f.Keys
+	s.byHash = byHash
+	s.mu.Unlock()
+	return nil
+}
+
+// Lookup 根据明文密钥查找记录，未找到时返回 nil。
+func (s *KeyStore) Lookup(plain string) *APIKey {
+	sum := sha256.Sum256([]byte(plain))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byHash[hex.EncodeToString(sum[:])]
+}
+
+// Add 生成新密钥并写入密钥库文件，返回仅此一次可见的明文密钥。
+func (s *KeyStore) Add(key APIKey) (string, error) {
+	plain, hash, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	key.Hash = hash
+	if err := validateKey(&key); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.ID == key.ID {
+			return "", fmt.Errorf("id 已存在: %s", key.ID)
+		}
+	}
+	keys := append(append([]APIKey(nil), s.keys...), key)
+	data, err := json.MarshalIndent(keyFile{Keys: keys}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return "", fmt.Errorf("写入密钥库失败: %w", err)
+	}
+
+	s.keys = keys
+	s.byHash = make(map[string]*APIKey, len(keys))
+	for i := range s.keys {
+		s.byHash[s.keys[i].Hash] = &s.keys[i]
+	}
+	return plain, nil
+}
+
+// Keys 返回密钥记录的副本，用于列表展示。
+func (s *KeyStore) Keys() []APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]APIKey(nil), s.keys...)
+}
+
+// GenerateKey 生成随机密钥及其 SHA-256 摘要（十六进制）。
+func GenerateKey() (plain, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("生成密钥失败: %w", err)
+	}
+	plain = apiKeyPrefix + hex.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(plain))
+	return plain, hex.EncodeToString(sum[:]), nil
+}
+
+func validateKey(k *APIKey) error {
+	if k.ID == "" {
+		return errors.New("id 不能为空")
+	}
+	if b, err := hex.DecodeString(k.Hash); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("%s: hash 必须为 64 位十六进制 SHA-256 摘要", k.ID)
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("%s: 至少需要一个 scope", k.ID)
+	}
+	for _, scope := range k.Scopes {
+		switch scope {
+		case ScopeLookup, ScopeBatch, ScopeAdmin:
+		default:
+			return fmt.Errorf("%s: 未知的 scope %q", k.ID, scope)
+		}
+	}
+	if k.Rate < 0 || k.Burst < 0 || k.DailyQuota < 0 {
+		return fmt.Errorf("%s: 限流参数不能为负数", k.ID)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// openapiYAML 为手工维护的 OpenAPI 3 文档，新增或修改路由时需同步更新。
+//
+//go:embed openapi.yaml
+var openapiYAML []byte
+
+// openapiSpec 为解析后的文档，供 /openapi.json 输出使用；路由覆盖由 openapi_test.go 检查。
+var openapiSpec = mustLoadOpenAPI(openapiYAML)
+
+func mustLoadOpenAPI(data []byte) map[string]any {
+	var spec map[string]any
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		panic(fmt.Sprintf("内置 OpenAPI 文档解析失败: %v", err))
+	}
+	if _, ok := spec["paths"].(map[string]any); !ok {
+		panic("内置 OpenAPI 文档缺少 paths")
+	}
+	return spec
+}
+
+// serveOpenAPI 输出 JSON 格式的 OpenAPI 文档，servers 按当前访问地址生成，便于直接在文档页中试用。
+func serveOpenAPI(c *gin.Context) {
+	spec := make(map[string]any, len(openapiSpec)+1)
+	for k, v := range openapiSpec {
+		spec[k] = v
+	}
+	spec["servers"] = []map[string]string{{"url": baseURL(c)}}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPICoverage 确认每条已注册的路由在 openapi.yaml 中都有对应条目，避免文档与实现脱节；
+// 可选的认证与兼容接口全部启用，确保它们同样被覆盖。
+func TestOpenAPICoverage(t *testing.T) {
+	router := newTestRouter(t, Options{
+		// 仅注册路由，不会处理请求，无需密钥库
+		Auth:   NewAuthenticator(nil, false),
+		Compat: []string{CompatIPAPI, CompatIPInfo, CompatTaobao, CompatPconline},
+	})
+
+	paths := openapiSpec["paths"].(map[string]any)
+	var missing []string
+	for _, r := range router.Routes() {
+		item, _ := paths[openapiPath(r.Path)].(map[string]any)
+		if _, ok := item[strings.ToLower(r.Method)]; !ok {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		t.Fatalf("以下路由未在 openapi.yaml 中描述: %s", strings.Join(missing, ", "))
+	}
+}
+
+// openapiPath 将 Gin 路由路径转换为 OpenAPI 路径模板，如 /ip/:ip → /ip/{ip}、/cidr/*cidr → /cidr/{cidr}。
+func openapiPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// TestOpenAPIPageSameOrigin 确认 /openapi 页面及其内容安全策略只引用本站资源。
+func TestOpenAPIPageSameOrigin(t *testing.T) {
+	router := newTestRouter(t, Options{})
+	w := serve(router, http.MethodGet, "/openapi", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); csp != pageCSP {
+		t.Errorf("Content-Security-Policy = %q，期望 %q", csp, pageCSP)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "://") {
+		t.Errorf("页面引用了外部资源:\n%s", body)
+	}
+	for _, asset := range []string{"/static/openapi.js", "/static/openapi.css"} {
+		if !strings.Contains(body, asset) {
+			t.Errorf("页面未引用 %s", asset)
+		}
+		if w := serve(router, http.MethodGet, asset, nil); w.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d", asset, w.Code)
+		}
+	}
+}
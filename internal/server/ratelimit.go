@@ -0,0 +1,304 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextClientKey 为限流计数主体在 gin.Context 中的键；认证中间件写入后按 API Key 计数，否则按客户端 IP 计数。
+const contextClientKey = "ratelimit.client"
+
+const (
+	quotaFlushInterval = 30 * time.Second
+	bucketSweepEvery   = 1024
+)
+
+// RateLimitOptions 为令牌桶限流与每日配额的参数。
+type RateLimitOptions struct {
+	Rate       float64 // 每秒补充的令牌数
+	Burst      int     // 桶容量，即允许的瞬时突发请求数
+	DailyQuota int64   // 每个主体每日请求上限，0 表示不限
+	QuotaFile  string  // 配额计数持久化文件，为空时仅保存在内存中
+}
+
+// RateLimiter 按客户端 IP 或 API Key 实施令牌桶限流，并统计每日配额。
+type RateLimiter struct {
+	opts RateLimitOptions
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+
+	quota *quotaStore
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// refillTime 返回补充 tokens 个令牌所需的时间。
+func (b *bucket) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+// NewRateLimiter 创建限流器并加载已持久化的配额计数；配置了 QuotaFile 时会定期落盘，需调用 Close 释放。
+func NewRateLimiter(opts RateLimitOptions) (*RateLimiter, error) {
+	if opts.Rate <= 0 || opts.Burst <= 0 {
+		return nil, errors.New("限流速率与桶容量必须为正数")
+	}
+	quota, err := loadQuotaStore(opts.QuotaFile)
+	if err != nil {
+		return nil, err
+	}
+	l := &RateLimiter{
+		opts:    opts,
+		buckets: make(map[string]*bucket),
+		quota:   quota,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.flushLoop()
+	return l, nil
+}
+
+// Close 停止后台落盘并写入最新的配额计数。
+func (l *RateLimiter) Close() error {
+	close(l.stop)
+	<-l.done
+	return l.quota.save()
+}
+
+// middleware 返回 Gin 中间件：超出速率或配额时返回 429，并通过 X-RateLimit-* 头告知剩余额度。
+// trustForwarded 为 false（未配置可信代理）时忽略转发头，按连接的对端地址计数，避免伪造请求头绕过限流。
+func (l *RateLimiter) middleware(trustForwarded bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := clientKey(c, trustForwarded)
+		now := time.Now()
+		rate, burst, quota := l.limitsFor(c)
+
+		allowed, remaining, retryAfter, full := l.take(key, now, rate, burst)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(now.Add(full).Unix(), 10))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			abortWithError(c, http.StatusTooManyRequests, codeRateLimited)
+			return
+		}
+
+		if quota > 0 {
+			used, ok := l.quota.add(key, now, quota)
+			reset := nextDay(now)
+			c.Header("X-RateLimit-Quota-Limit", strconv.FormatInt(quota, 10))
+			c.Header("X-RateLimit-Quota-Remaining", strconv.FormatInt(max(quota-used, 0), 10))
+			c.Header("X-RateLimit-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if !ok {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(reset.Sub(now).Seconds()))))
+				abortWithError(c, http.StatusTooManyRequests, codeQuotaExceeded)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// clientKey 返回限流计数主体：优先使用认证中间件写入的标识，其次为客户端 IP；
+// 仅在 trustForwarded 时采用转发头中的地址，否则使用连接的对端地址。
+func clientKey(c *gin.Context, trustForwarded bool) string {
+	if key := c.GetString(contextClientKey); key != "" {
+		return key
+	}
+	if !trustForwarded {
+		return "ip:" + c.RemoteIP()
+	}
+	if ip, err := extractClientIPv4(c); err == nil {
+		return "ip:" + ip
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// limitsFor 返回当前请求适用的速率、桶容量与每日配额，API Key 配置的专属参数优先。
+func (l *RateLimiter) limitsFor(c *gin.Context) (float64, int, int64) {
+	rate, burst, quota := l.opts.Rate, l.opts.Burst, l.opts.DailyQuota
+	if key := apiKeyFrom(c); key != nil {
+		if key.Rate > 0 {
+			rate = key.Rate
+		}
+		if key.Burst > 0 {
+			burst = key.Burst
+		}
+		if key.DailyQuota > 0 {
+			quota = key.DailyQuota
+		}
+	}
+	return rate, burst, quota
+}
+
+// take 尝试从 key 对应的令牌桶中取出一个令牌，返回是否放行、剩余令牌数、
+// 下一个令牌的等待时间以及桶补满所需时间。
+func (l *RateLimiter) take(key string, now time.Time, rate float64, burst int) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls%bucketSweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	// 参数可能随密钥库热加载而变化，每次按最新值计算
+	b.rate, b.burst = rate, float64(burst)
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	retryAfter := time.Duration(0)
+	if !allowed {
+		retryAfter = b.refillTime(1 - b.tokens)
+	}
+	return allowed, int(b.tokens), retryAfter, b.refillTime(b.burst - b.tokens)
+}
+
+// sweep 清理已补满的令牌桶，避免长期运行时按 IP 累积的状态无限增长。
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= b.refillTime(b.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *RateLimiter) flushLoop() {
+	defer close(l.done)
+	ticker := time.NewTicker(quotaFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.quota.save(); err != nil {
+				log.Printf("保存配额计数失败: %v", err)
+			}
+		}
+	}
+}
+
+// quotaStore 记录当日各主体的请求次数，跨天自动清零，并可持久化到本地文件以便重启后延续。
+type quotaStore struct {
+	path string
+
+	mu     sync.Mutex
+	day    string
+	counts map[string]int64
+	dirty  bool
+}
+
+type quotaFile struct {
+	Day    string           `json:"day"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+func loadQuotaStore(path string) (*quotaStore, error) {
+	s := &quotaStore{path: path, counts: make(map[string]int64)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配额文件失败: %w", err)
+	}
+	var f quotaFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("配额文件格式不合法: %w", err)
+	}
+	if f.Counts != nil {
+		s.day, s.counts = f.Day, f.Counts
+	}
+	return s, nil
+}
+
+// add 为 key 计入一次请求，返回当日已用次数以及是否仍在配额内；超出配额的请求不计数。
+func (s *quotaStore) add(key string, now time.Time, limit int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if day := now.Format(time.DateOnly); day != s.day {
+		s.day = day
+		s.counts = make(map[string]int64)
+		s.dirty = true
+	}
+	used := s.counts[key]
+	if used >= limit {
+		return used, false
+	}
+	s.counts[key] = used + 1
+	s.dirty = true
+	return used + 1, true
+}
+
+// save 在计数有变化时以临时文件加重命名的方式写入，避免中断时留下半截文件。
+func (s *quotaStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(quotaFile{Day: s.day, Counts: s.counts})
+	s.dirty = false
+	s.mu.Unlock()
+	if err == nil {
+		err = writeFileAtomic(s.path, data)
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func nextDay(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+}
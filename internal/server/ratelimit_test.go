@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func newTestLimiter(t *testing.T) *RateLimiter {
+	t.Helper()
+	limiter, err := NewRateLimiter(RateLimitOptions{Rate: 1, Burst: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+	return limiter
+}
+
+// statuses 以不同的 X-Forwarded-For 连续请求 n 次，返回各次的状态码。
+func statuses(router http.Handler, n int) []int {
+	forwarded := []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4", "5.5.5.5"}
+	var out []int
+	for i := 0; i < n; i++ {
+		w := serve(router, http.MethodGet, "/ip/8.8.8.8", http.Header{
+			"X-Forwarded-For": {forwarded[i%len(forwarded)]},
+			"X-Real-Ip":       {forwarded[i%len(forwarded)]},
+		})
+		out = append(out, w.Code)
+	}
+	return out
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	router := newTestRouter(t, Options{RateLimiter: newTestLimiter(t)})
+	got := statuses(router, 4)
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("未配置可信代理时伪造的转发头不应获得新的令牌桶: 状态码 %v，期望 %v", got, want)
+		}
+	}
+}
+
+func TestRateLimitTrustedProxyUsesForwardedFor(t *testing.T) {
+	router := newTestRouter(t, Options{
+		RateLimiter:    newTestLimiter(t),
+		TrustedProxies: []string{"192.0.2.1"},
+	})
+	for i, code := range statuses(router, 4) {
+		if code != http.StatusOK {
+			t.Fatalf("第 %d 次请求状态码 %d：可信代理转发的不同客户端应分别计数", i+1, code)
+		}
+	}
+}
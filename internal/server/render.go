@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// 响应格式，可通过 format 查询参数或 Accept 请求头选择
+const (
+	formatJSON    = "json"
+	formatXML     = "xml"
+	formatText    = "text"
+	formatCSV     = "csv"
+	formatMsgPack = "msgpack"
+)
+
+// formatAliases 为 format 查询参数的可选值。
+var formatAliases = map[string]string{
+	"json":    formatJSON,
+	"xml":     formatXML,
+	"text":    formatText,
+	"txt":     formatText,
+	"plain":   formatText,
+	"csv":     formatCSV,
+	"msgpack": formatMsgPack,
+}
+
+// mediaTypes 为 Accept 请求头中可识别的媒体类型。
+var mediaTypes = map[string]string{
+	"application/json":        formatJSON,
+	"application/xml":         formatXML,
+	"text/xml":                formatXML,
+	"text/plain":              formatText,
+	"text/csv":                formatCSV,
+	"application/msgpack":     formatMsgPack,
+	"application/x-msgpack":   formatMsgPack,
+	"application/vnd.msgpack": formatMsgPack,
+}
+
+// textRenderer 由支持纯文本输出的响应实现。
+type textRenderer interface {
+	text() string
+}
+
+// csvRenderer 由支持 CSV 输出的响应实现，首行为表头。
+type csvRenderer interface {
+	csvRecords() [][]string
+}
+
+// respond 按协商结果输出响应；format 参数不合法时返回 400。
+func respond(c *gin.Context, status int, v any) {
+	format, ok := negotiateFormat(c)
+	if !ok {
+		renderAs(c, formatJSON, http.StatusBadRequest, newProblem(c, http.StatusBadRequest, codeInvalidFormat))
+		return
+	}
+	renderAs(c, format, status, v)
+}
+
+func renderAs(c *gin.Context, format string, status int, v any) {
+	_, isProblem := v.(problem)
+	switch format {
+	case formatXML:
+		if isProblem {
+			c.Header("Content-Type", "application/problem+xml; charset=utf-8")
+		}
+		c.Render(status, render.XML{Data: v})
+	case formatMsgPack:
+		c.Render(status, render.MsgPack{Data: v})
+	case formatText:
+		if t, ok := v.(textRenderer); ok {
+			c.String(status, "%s\n", t.text())
+			return
+		}
+		c.JSON(status, v)
+	case formatCSV:
+		if r, ok := v.(csvRenderer); ok {
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			w.WriteAll(r.csvRecords())
+			c.Data(status, "text/csv; charset=utf-8", buf.Bytes())
+			return
+		}
+		c.JSON(status, v)
+	default:
+		// 预先设置的 Content-Type 不会被 gin 的 JSON 渲染覆盖
+		if isProblem {
+			c.Header("Content-Type", "application/problem+json; charset=utf-8")
+		}
+		c.JSON(status, v)
+	}
+}
+
+// negotiateFormat 优先读取 format 查询参数，其次按 Accept 请求头的 q 值选择格式，默认 JSON。
+// 浏览器直接访问时 Accept 同时包含 text/html 与 application/xml，此时仍返回 JSON。
+func negotiateFormat(c *gin.Context) (string, bool) {
+	if v := strings.ToLower(strings.TrimSpace(c.Query("format"))); v != "" {
+		format, ok := formatAliases[v]
+		return format, ok
+	}
+
+	c.Writer.Header().Add("Vary", "Accept")
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return formatJSON, true
+	}
+	if strings.Contains(accept, "text/html") {
+		return formatJSON, true
+	}
+
+	type candidate struct {
+		format string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		media, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		format, ok := mediaTypes[strings.ToLower(strings.TrimSpace(media))]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{format: format, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return formatJSON, true
+	}
+	// q 值相同时保持 Accept 中的先后顺序
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].format, true
+}
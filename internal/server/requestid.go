@@ -0,0 +1,39 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextRequestID 为请求 ID 在 gin.Context 中的键。
+const contextRequestID = "request.id"
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID 限定上游传入的请求 ID 字符集与长度，避免日志注入。
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestID 返回请求 ID 中间件：沿用上游（如网关）传入的 X-Request-ID，缺失或不合法时生成新的 ID，
+// 并写入响应头，便于将错误响应与服务端日志对应起来。
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(contextRequestID, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
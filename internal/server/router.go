@@ -1,38 +1,126 @@
 package server
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"ipservice/internal/i18n"
 	"ipservice/internal/ipdb"
 )
 
+// Options 为路由层的可配置项，零值字段使用默认值。
+type Options struct {
+	// TrustedProxies 为可信代理的 IP 或网段，仅信任来自这些地址的转发头；为空时查询沿用 Gin 默认行为，限流则只按对端地址计数
+	TrustedProxies []string
+	// BatchLimit 为批量查询单次最多 IP 数
+	BatchLimit int
+	// CIDRLimit 为网段查询单次最多返回记录数
+	CIDRLimit int
+	// RateLimiter 非空时对查询接口实施限流与每日配额，健康检查与文档页不受影响
+	RateLimiter *RateLimiter
+	// Auth 非空时查询接口需携带具备相应 scope 的 API Key，并注册 /admin 管理接口
+	Auth *Authenticator
+	// CORS 非空时为浏览器跨域请求补充响应头并应答预检请求
+	CORS *CORSOptions
+	// Compat 为启用的兼容接口分组（CompatIPAPI 等），按第三方 IP 接口的格式返回查询结果
+	Compat []string
+	// WebDir 为页面资源覆盖目录，其中的 templates/、static/、docs/ 同名文件优先于内置版本
+	WebDir string
+}
+
+const (
+	defaultBatchLimit = 100
+	defaultMaxCIDR    = 10000
+)
+
 // NewRouter 构建 Gin 引擎并注册全部路由。
-func NewRouter(service *ipdb.Service) *gin.Engine {
+func NewRouter(service *ipdb.Service, opts Options) (*gin.Engine, error) {
 	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery())
+	router.Use(requestID(), localize(), gin.Logger(), gin.Recovery())
+	if opts.CORS != nil {
+		router.Use(cors(*opts.CORS))
+		if opts.CORS.JSONP {
+			router.Use(jsonp())
+		}
+	}
+	if len(opts.TrustedProxies) > 0 {
+		if err := router.SetTrustedProxies(opts.TrustedProxies); err != nil {
+			return nil, fmt.Errorf("可信代理配置不合法: %w", err)
+		}
+	}
+	if opts.BatchLimit <= 0 {
+		opts.BatchLimit = defaultBatchLimit
+	}
+	if opts.CIDRLimit <= 0 {
+		opts.CIDRLimit = defaultMaxCIDR
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, codeRouteNotFound)
+	})
 
 	// 文档路由（根路径展示 API 文档）
-	registerDocRoutes(router)
+	assets, err := newAssets(opts.WebDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := registerDocRoutes(router, assets, opts); err != nil {
+		return nil, err
+	}
 
-	handler := &handler{service: service}
+	handler := &handler{service: service, opts: opts}
 
 	router.GET("/health", handler.health)
-	router.GET("/ip", handler.queryByClient)
-	router.GET("/ip/:ip", handler.queryByPath)
-	router.POST("/ip", handler.queryByBody)
+	router.GET("/meta", handler.meta)
+
+	lookup := router.Group("/", opts.guard(ScopeLookup)...)
+	lookup.GET("/ip", handler.queryByClient)
+	lookup.GET("/ip/:ip", handler.queryByPath)
+	lookup.GET("/cidr/*cidr", handler.queryByCIDR)
+	lookup.POST("/ip", handler.queryByBody)
+	lookup.GET("/search", handler.search)
+
+	batch := router.Group("/", opts.guard(ScopeBatch)...)
+	batch.POST("/ip/batch", handler.queryBatch)
+
+	if err := registerCompatRoutes(router, handler, opts.Compat); err != nil {
+		return nil, err
+	}
+
+	// 管理接口仅在启用认证时注册，避免未经授权触发热加载
+	if opts.Auth != nil {
+		admin := router.Group("/admin", opts.guard(ScopeAdmin)...)
+		admin.POST("/reload", handler.reload)
+	}
 
-	return router
+	return router, nil
+}
+
+// guard 返回访问指定 scope 接口所需的中间件：先认证，再按认证结果限流。
+func (o Options) guard(scope string) []gin.HandlerFunc {
+	var chain []gin.HandlerFunc
+	if o.Auth != nil {
+		chain = append(chain, o.Auth.require(scope))
+	}
+	if o.RateLimiter != nil {
+		chain = append(chain, o.RateLimiter.middleware(len(o.TrustedProxies) > 0))
+	}
+	return chain
 }
 
 // handler 组合领域服务，对外提供 HTTP 处理逻辑。
 type handler struct {
 	service *ipdb.Service
+	opts    Options
 }
 
 type ipRequest struct {
@@ -40,16 +128,84 @@ type ipRequest struct {
 }
 
 type ipResponse struct {
-	IP      string   `json:"ip"`
-	Country string   `json:"country"`
-	Area    string   `json:"area"`
-	Raw     []string `json:"raw"`
+	XMLName     xml.Name `json:"-" xml:"result"`
+	IP          string   `json:"ip" xml:"ip"`
+	Country     string   `json:"country" xml:"country"`
+	Area        string   `json:"area" xml:"area"`
+	Scope       string   `json:"scope" xml:"scope"`
+	CountryCode string   `json:"country_code,omitempty" xml:"country_code,omitempty"`
+	CountryName string   `json:"country_name,omitempty" xml:"country_name,omitempty"`
+	Continent   string   `json:"continent,omitempty" xml:"continent,omitempty"`
+	Province    string   `json:"province,omitempty" xml:"province,omitempty"`
+	City        string   `json:"city,omitempty" xml:"city,omitempty"`
+	Adcode      string   `json:"adcode,omitempty" xml:"adcode,omitempty"`
+	Lat         float64  `json:"lat,omitempty" xml:"lat,omitempty"`
+	Lon         float64  `json:"lon,omitempty" xml:"lon,omitempty"`
+	Range       *ipRange `json:"range,omitempty" xml:"range,omitempty"`
+	Raw         []string `json:"raw" xml:"raw>item"`
+}
+
+// ipRange 为命中的 qqwry 区段及其拆分后的 CIDR 前缀。
+type ipRange struct {
+	Start string   `json:"start" xml:"start"`
+	End   string   `json:"end" xml:"end"`
+	CIDRs []string `json:"cidrs" xml:"cidrs>item"`
+}
+
+// ipCSVHeader 为单个查询结果的 CSV 表头，批量查询在末尾追加 error 列。
+var ipCSVHeader = []string{"ip", "country", "area", "scope", "country_code", "country_name", "continent", "province", "city", "adcode", "lat", "lon"}
+
+// text 返回纯文本形式的归属信息，如 "美国 谷歌公司"，便于脚本直接使用。
+func (r ipResponse) text() string {
+	return strings.TrimSpace(r.Country + " " + r.Area)
+}
+
+func (r ipResponse) csvRecords() [][]string {
+	return [][]string{ipCSVHeader, r.csvRow()}
+}
+
+func (r ipResponse) csvRow() []string {
+	coord := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return []string{r.IP, r.Country, r.Area, r.Scope, r.CountryCode, r.CountryName, r.Continent, r.Province, r.City, r.Adcode, coord(r.Lat), coord(r.Lon)}
 }
 
 func (h *handler) health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// metaResponse 为当前加载的数据文件信息。
+type metaResponse struct {
+	Version  string    `json:"version"`
+	Records  int       `json:"records"`
+	Size     int64     `json:"size"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// meta 返回数据版本、记录数与加载时间，便于确认热更新是否生效。
+func (h *handler) meta(c *gin.Context) {
+	m := h.service.Metadata()
+	c.JSON(http.StatusOK, metaResponse{Version: m.Version, Records: m.Records, Size: m.Size, LoadedAt: m.LoadedAt})
+}
+
+// reload 重新加载数据文件与密钥库，需要 admin 权限。
+func (h *handler) reload(c *gin.Context) {
+	if err := h.service.Reload(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if err := h.opts.Auth.Store().Reload(); err != nil {
+		log.Printf("请求 %s 重新加载密钥库失败: %v", c.GetString(contextRequestID), err)
+		respondError(c, http.StatusInternalServerError, codeReloadFailed)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
 func (h *handler) queryByPath(c *gin.Context) {
 	ip := c.Param("ip")
 	h.lookup(c, ip)
@@ -58,7 +214,7 @@ func (h *handler) queryByPath(c *gin.Context) {
 func (h *handler) queryByBody(c *gin.Context) {
 	var req ipRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeBindError(c, err)
 		return
 	}
 	h.lookup(c, req.IP)
@@ -68,7 +224,7 @@ func (h *handler) queryByBody(c *gin.Context) {
 func (h *handler) queryByClient(c *gin.Context) {
 	ip, err := extractClientIPv4(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeClientIPError(c, err)
 		return
 	}
 
@@ -78,59 +234,90 @@ func (h *handler) queryByClient(c *gin.Context) {
 func (h *handler) lookup(c *gin.Context, ip string) {
 	result, err := h.service.Lookup(ip)
 	if err != nil {
-		status := http.StatusInternalServerError
-		switch {
-		case errors.Is(err, ipdb.ErrInvalidIP),
-			errors.Is(err, ipdb.ErrIPv6NotSupported),
-			errors.Is(err, ipdb.ErrDecodeCountry),
-			errors.Is(err, ipdb.ErrDecodeArea):
-			status = http.StatusBadRequest
-		case errors.Is(err, ipdb.ErrNotFound):
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": toUserMessage(err)})
+		writeError(c, err)
 		return
 	}
 
+	respond(c, http.StatusOK, newIPResponse(result, displayLang(c)))
+}
+
+// newIPResponse 将查询结果转换为响应结构，lang 决定 country_name 的语言；
+// 英文模式下 province、city 在收录了英文名称时同样使用英文。
+func newIPResponse(result ipdb.Result, lang string) ipResponse {
 	resp := ipResponse{
-		IP:      result.IP,
-		Country: result.Country,
-		Area:    result.Area,
-		Raw:     []string{result.Country, result.Area},
+		IP:          result.IP,
+		Country:     result.Country,
+		Area:        result.Area,
+		Scope:       string(result.Scope),
+		CountryCode: result.CountryCode,
+		CountryName: result.CountryNameZH,
+		Continent:   result.Continent,
+		Province:    result.Province,
+		City:        result.City,
+		Adcode:      result.Adcode,
+		Lat:         result.Latitude,
+		Lon:         result.Longitude,
+		Raw:         []string{result.Country, result.Area},
 	}
-	c.JSON(http.StatusOK, resp)
+	// 跳过数据库查询的非公网地址没有命中区段
+	if result.RangeStart != 0 || result.RangeEnd != 0 {
+		r := ipdb.Range{Start: result.RangeStart, End: result.RangeEnd}
+		resp.Range = &ipRange{Start: r.StartIP(), End: r.EndIP(), CIDRs: r.CIDRs()}
+	}
+	if lang == i18n.EN {
+		resp.CountryName = result.CountryNameEN
+		if result.ProvinceEN != "" {
+			resp.Province = result.ProvinceEN
+		}
+		if result.CityEN != "" {
+			resp.City = result.CityEN
+		}
+	}
+	return resp
 }
 
-func extractClientIPv4(c *gin.Context) (string, error) {
-	if ip := pickIPv4(c.GetHeader("X-Forwarded-For")); ip != "" {
-		return ip, nil
+// contextLang 为协商后的展示语言在 gin.Context 中的键。
+const contextLang = "i18n.lang"
+
+// localize 返回语言协商中间件：优先读取 lang 查询参数，其次为 Accept-Language 请求头，默认简体中文。
+func localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextLang, i18n.Negotiate(c.Query("lang"), c.GetHeader("Accept-Language")))
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
 	}
-	if ip := pickIPv4(c.GetHeader("X-Real-IP")); ip != "" {
-		return ip, nil
+}
+
+// displayLang 返回当前请求的展示语言。
+func displayLang(c *gin.Context) string {
+	if lang := c.GetString(contextLang); lang != "" {
+		return lang
 	}
+	return i18n.Negotiate(c.Query("lang"), c.GetHeader("Accept-Language"))
+}
+
+var errNoClientIP = errors.New("无法识别客户端IP")
+
+// clientIPv6Error 表示客户端来源地址为 IPv6，qqwry.dat 无法查询。
+type clientIPv6Error struct {
+	ip string
+}
 
+func (e *clientIPv6Error) Error() string {
+	return "当前qqwry.dat仅支持IPv4查询，检测到: " + e.ip
+}
+
+// extractClientIPv4 解析客户端来源 IP；转发头（X-Forwarded-For、X-Real-IP）的信任范围由 Options.TrustedProxies 决定。
+func extractClientIPv4(c *gin.Context) (string, error) {
 	raw := c.ClientIP()
 	if raw == "" {
-		return "", errors.New("无法识别客户端IP")
+		return "", errNoClientIP
 	}
 	if ip := parseIPv4(raw); ip != "" {
 		return ip, nil
 	}
 
-	return "", fmt.Errorf("当前qqwry.dat仅支持IPv4查询，检测到: %s", raw)
-}
-
-func pickIPv4(header string) string {
-	if header == "" {
-		return ""
-	}
-	parts := strings.Split(header, ",")
-	for _, part := range parts {
-		if ip := parseIPv4(part); ip != "" {
-			return ip
-		}
-	}
-	return ""
+	return "", &clientIPv6Error{ip: raw}
 }
 
 func parseIPv4(value string) string {
@@ -143,22 +330,3 @@ func parseIPv4(value string) string {
 	}
 	return ""
 }
-
-// toUserMessage 将内部错误标准化为面向用户的中文提示，避免泄露内部标识。
-func toUserMessage(err error) string {
-	switch {
-	case errors.Is(err, ipdb.ErrInvalidIP):
-		return "无法解析 IP"
-	case errors.Is(err, ipdb.ErrIPv6NotSupported):
-		return "当前仅支持 IPv4 查询"
-	case errors.Is(err, ipdb.ErrNotFound):
-		return "未找到 IP 的归属信息"
-	case errors.Is(err, ipdb.ErrDecodeCountry):
-		return "国家字段编码转换失败"
-	case errors.Is(err, ipdb.ErrDecodeArea):
-		return "区域字段编码转换失败"
-	default:
-		return err.Error()
-	}
-}
-
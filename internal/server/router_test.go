@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"ipservice/internal/ipdb"
+	"ipservice/pkg/qqwry/qqwrytest"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestService 使用内存构造的样例数据创建查询服务。
+func newTestService(t *testing.T) *ipdb.Service {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "qqwry.dat")
+	if err := os.WriteFile(path, qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := ipdb.NewService(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func newTestRouter(t *testing.T, opts Options) *gin.Engine {
+	t.Helper()
+	router, err := NewRouter(newTestService(t), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+// serve 发送请求并返回响应；httptest 请求的对端地址固定为 192.0.2.1:1234。
+func serve(router http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLookupByPath(t *testing.T) {
+	router := newTestRouter(t, Options{})
+	w := serve(router, http.MethodGet, "/ip/1.2.3.4", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"country":"江苏省苏州市"`) || !strings.Contains(body, `"start":"1.2.2.0"`) {
+		t.Fatalf("响应缺少归属信息: %s", body)
+	}
+}
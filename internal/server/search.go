@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"ipservice/internal/ipdb"
+)
+
+type rangeResponse struct {
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Country string `json:"country"`
+	Area    string `json:"area"`
+}
+
+type searchResponse struct {
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	Ranges   []rangeResponse `json:"ranges"`
+	CIDRs    []string        `json:"cidrs"`
+}
+
+// search 按国家/区域反向检索地址区段，例如 /search?country=江苏&area=移动。
+func (h *handler) search(c *gin.Context) {
+	page, ok := positiveQuery(c, "page")
+	if !ok {
+		return
+	}
+	pageSize, ok := positiveQuery(c, "page_size")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Search(ipdb.SearchQuery{
+		Country:  c.Query("country"),
+		Area:     c.Query("area"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newSearchResponse(result))
+}
+
+// positiveQuery 读取正整数查询参数，未提供时返回 0 表示使用默认值；取值无法解析或不大于 0 时输出 400 并返回 false。
+func positiveQuery(c *gin.Context, name string) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		respondError(c, http.StatusBadRequest, codeInvalidRequest, name)
+		return 0, false
+	}
+	return v, true
+}
+
+func newSearchResponse(result ipdb.SearchResult) searchResponse {
+	resp := searchResponse{
+		Total:    result.Total,
+		Page:     result.Page,
+		PageSize: result.PageSize,
+		Ranges:   make([]rangeResponse, 0, len(result.Ranges)),
+		CIDRs:    result.CIDRs,
+	}
+	if resp.CIDRs == nil {
+		resp.CIDRs = []string{}
+	}
+	for _, r := range result.Ranges {
+		resp.Ranges = append(resp.Ranges, newRangeResponse(r))
+	}
+	return resp
+}
+
+func newRangeResponse(r ipdb.Range) rangeResponse {
+	return rangeResponse{
+		Start:   r.StartIP(),
+		End:     r.EndIP(),
+		Country: r.Country,
+		Area:    r.Area,
+	}
+}
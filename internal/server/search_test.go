@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestSearchPaging(t *testing.T) {
+	router := newTestRouter(t, Options{})
+
+	// 样例数据中国家含“江苏”的区段共 3 条
+	tests := []struct {
+		name     string
+		page     string
+		pageSize string
+		status   int
+		ranges   int
+	}{
+		{"默认分页", "", "", http.StatusOK, 3},
+		{"超出末页", "3", "2", http.StatusOK, 0},
+		{"最大页码", fmt.Sprint(math.MaxInt), "1000", http.StatusOK, 0},
+		{"页码乘积溢出", "9223372036854777", "1000", http.StatusOK, 0},
+		{"页码为 0", "0", "2", http.StatusBadRequest, 0},
+		{"负数页码", "-1", "2", http.StatusBadRequest, 0},
+		{"页码超出整数范围", "99999999999999999999", "2", http.StatusBadRequest, 0},
+		{"页码不是数字", "abc", "2", http.StatusBadRequest, 0},
+		{"每页条数为 0", "1", "0", http.StatusBadRequest, 0},
+		{"每页条数超过上限", "1", "1001", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"country": {"江苏"}}
+			if tt.page != "" {
+				query.Set("page", tt.page)
+			}
+			if tt.pageSize != "" {
+				query.Set("page_size", tt.pageSize)
+			}
+			w := serve(router, http.MethodGet, "/search?"+query.Encode(), nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d，期望 %d，body = %s", w.Code, tt.status, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp searchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Total != 3 || len(resp.Ranges) != tt.ranges {
+				t.Fatalf("total = %d，区段数 = %d，期望 3 与 %d", resp.Total, len(resp.Ranges), tt.ranges)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// 首页查询界面：单个与批量查询、地图标注、数据版本与本地查询历史。
+// 文案由页面内的 #messages 提供，请求均使用相对地址并携带 lang 参数，满足 CSP 的 connect-src 'self'。
+(function () {
+  'use strict';
+
+  const lang = document.documentElement.lang;
+  const messages = JSON.parse(document.getElementById('messages').textContent);
+  const historyKey = 'ipservice.history';
+  const historyLimit = 20;
+
+  function t(key, ...args) {
+    let i = 0;
+    return (messages[key] || key).replace(/%[sd]/g, () => String(args[i++] ?? ''));
+  }
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function el(tag, text) {
+    const node = document.createElement(tag);
+    if (text !== undefined) {
+      node.textContent = text;
+    }
+    return node;
+  }
+
+  function withLang(path) {
+    return path + (path.includes('?') ? '&' : '?') + 'lang=' + encodeURIComponent(lang);
+  }
+
+  // request 返回解析后的 JSON；错误响应（RFC 7807）转换为带错误码的异常
+  async function request(path, init) {
+    const resp = await fetch(withLang(path), init);
+    const body = await resp.json();
+    if (!resp.ok) {
+      throw new Error(body.code ? body.message + ' (' + body.code + ')' : resp.statusText);
+    }
+    return body;
+  }
+
+  function status(id, text) {
+    const node = $(id);
+    node.textContent = text || '';
+    node.hidden = !text;
+  }
+
+  function place(r) {
+    return [r.country, r.area].filter(Boolean).join(' ');
+  }
+
+  function rangeText(r) {
+    return r.range ? r.range.start + ' – ' + r.range.end : '';
+  }
+
+  function hasCoordinates(r) {
+    return typeof r.lat === 'number' && typeof r.lon === 'number';
+  }
+
+  // 单个查询
+
+  function renderResult(r) {
+    const rows = [
+      ['field.ip', r.ip],
+      ['field.location', place(r)],
+      ['field.scope', r.scope],
+      ['field.country_name', r.country_name],
+      ['field.country_code', r.country_code],
+      ['field.continent', r.continent],
+      ['field.province', r.province],
+      ['field.city', r.city],
+      ['field.adcode', r.adcode],
+      ['field.coordinates', hasCoordinates(r) ? r.lat + ', ' + r.lon : ''],
+      ['field.range', rangeText(r)],
+      ['field.cidrs', r.range ? r.range.cidrs.join(' ') : ''],
+    ];
+    const table = $('result');
+    table.replaceChildren();
+    for (const [key, value] of rows) {
+      if (!value) {
+        continue;
+      }
+      const tr = el('tr');
+      tr.append(el('th', t(key)), el('td', value));
+      table.append(tr);
+    }
+    table.hidden = false;
+    renderMap(r);
+  }
+
+  function renderMap(r) {
+    const map = $('map');
+    const marker = $('map-marker');
+    const link = $('map-link');
+    if (!r || !hasCoordinates(r)) {
+      marker.setAttribute('visibility', 'hidden');
+      link.hidden = true;
+      $('map-caption').textContent = r ? t('docs.no_coordinates') : '';
+      return;
+    }
+    const scale = Number(map.dataset.scale);
+    marker.setAttribute('cx', ((r.lon - Number(map.dataset.west)) * scale).toFixed(1));
+    marker.setAttribute('cy', ((Number(map.dataset.north) - r.lat) * scale).toFixed(1));
+    marker.setAttribute('visibility', 'visible');
+    link.href = 'https://www.openstreetmap.org/?mlat=' + r.lat + '&mlon=' + r.lon + '#map=9/' + r.lat + '/' + r.lon;
+    link.hidden = false;
+    $('map-caption').textContent = [r.province, r.city].filter(Boolean).join(' ');
+  }
+
+  async function lookup(ip) {
+    status('lookup-status', t('docs.loading'));
+    try {
+      const r = await request(ip ? '/ip/' + encodeURIComponent(ip) : '/ip');
+      status('lookup-status', '');
+      $('ip').value = r.ip;
+      renderResult(r);
+      remember(r);
+    } catch (e) {
+      status('lookup-status', e.message);
+      $('result').hidden = true;
+      renderMap(null);
+    }
+  }
+
+  $('lookup-form').addEventListener('submit', (e) => {
+    e.preventDefault();
+    const ip = $('ip').value.trim();
+    if (!ip) {
+      status('lookup-status', t('docs.enter_ip'));
+      return;
+    }
+    lookup(ip);
+  });
+  $('lookup-self').addEventListener('click', () => lookup(''));
+
+  // 批量查询
+
+  $('batch-form').addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const ips = $('batch-ips').value.split(/[\s,]+/).filter(Boolean);
+    if (ips.length === 0) {
+      status('batch-status', t('docs.enter_ip'));
+      return;
+    }
+    status('batch-status', t('docs.loading'));
+    try {
+      const body = await request('/ip/batch', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ips }),
+      });
+      status('batch-status', '');
+      renderBatch(body.results);
+    } catch (err) {
+      status('batch-status', err.message);
+      $('batch-result').hidden = true;
+    }
+  });
+
+  function renderBatch(results) {
+    const table = $('batch-result');
+    table.replaceChildren();
+    const head = el('tr');
+    for (const key of ['field.ip', 'field.location', 'field.province', 'field.city', 'field.cidrs', 'field.error']) {
+      head.append(el('th', t(key)));
+    }
+    table.append(head);
+    for (const item of results) {
+      const r = item.result || {};
+      const tr = el('tr');
+      const ip = el('td');
+      const a = el('a', item.ip);
+      a.href = '#';
+      a.addEventListener('click', (e) => {
+        e.preventDefault();
+        lookup(item.ip);
+      });
+      ip.append(a);
+      tr.append(ip, el('td', place(r)), el('td', r.province || ''), el('td', r.city || ''),
+        el('td', r.range ? r.range.cidrs.join(' ') : ''), el('td', item.error || ''));
+      table.append(tr);
+    }
+    table.hidden = false;
+  }
+
+  // 查询历史，仅保存在 localStorage 中；浏览器禁用存储时静默跳过
+
+  function loadHistory() {
+    try {
+      const list = JSON.parse(localStorage.getItem(historyKey) || '[]');
+      return Array.isArray(list) ? list : [];
+    } catch (e) {
+      return [];
+    }
+  }
+
+  function saveHistory(list) {
+    try {
+      localStorage.setItem(historyKey, JSON.stringify(list));
+    } catch (e) {
+      // 忽略
+    }
+    renderHistory(list);
+  }
+
+  function remember(r) {
+    const list = loadHistory().filter((h) => h.ip !== r.ip);
+    list.unshift({ ip: r.ip, place: place(r), at: Date.now() });
+    saveHistory(list.slice(0, historyLimit));
+  }
+
+  function renderHistory(list) {
+    const ul = $('history-list');
+    ul.replaceChildren();
+    if (list.length === 0) {
+      ul.append(el('li', t('docs.history_empty')));
+      return;
+    }
+    for (const h of list) {
+      const li = el('li');
+      const a = el('a', h.ip);
+      a.href = '#';
+      a.addEventListener('click', (e) => {
+        e.preventDefault();
+        lookup(h.ip);
+      });
+      li.append(a, ' ' + (h.place || '') + ' · ', el('time', new Date(h.at).toLocaleString(lang)));
+      ul.append(li);
+    }
+  }
+
+  $('history-clear').addEventListener('click', () => saveHistory([]));
+
+  // 数据版本
+
+  async function loadMeta() {
+    try {
+      const m = await request('/meta');
+      $('meta').textContent = t('docs.meta', m.version || '-', m.records.toLocaleString(lang),
+        new Date(m.loaded_at).toLocaleString(lang));
+    } catch (e) {
+      // 元信息仅用于展示，失败时不影响查询
+    }
+  }
+
+  renderHistory(loadHistory());
+  loadMeta();
+})();
@@ -0,0 +1,193 @@
+// /openapi 页面的接口文档浏览器：读取同源的 /openapi.json，按标签列出接口的参数、请求体与响应，
+// 并展开引用的数据结构，全部资源内置，离线环境可用。
+(function () {
+  'use strict';
+
+  const root = document.getElementById('api');
+  let spec = {};
+
+  function el(tag, text, className) {
+    const node = document.createElement(tag);
+    if (text !== undefined) {
+      node.textContent = text;
+    }
+    if (className) {
+      node.className = className;
+    }
+    return node;
+  }
+
+  // resolve 展开本文档内的 $ref（形如 #/components/schemas/Name）
+  function resolve(obj) {
+    let depth = 0;
+    while (obj && obj.$ref && depth++ < 8) {
+      obj = obj.$ref.replace(/^#\//, '').split('/').reduce((node, key) => (node ? node[key] : undefined), spec);
+    }
+    return obj || {};
+  }
+
+  function refName(obj) {
+    return obj && obj.$ref ? obj.$ref.split('/').pop() : '';
+  }
+
+  // schemaNode 返回数据结构的简要类型描述，引用的结构以锚点链接表示
+  function schemaNode(schema) {
+    if (!schema) {
+      return el('span', '-');
+    }
+    const name = refName(schema);
+    if (name) {
+      const link = el('a', name);
+      link.href = '#schema-' + name;
+      return link;
+    }
+    if (schema.type === 'array') {
+      const span = el('span', 'array of ');
+      span.appendChild(schemaNode(schema.items));
+      return span;
+    }
+    let text = schema.type || 'object';
+    if (schema.format) {
+      text += ' (' + schema.format + ')';
+    }
+    if (schema.enum) {
+      text += ': ' + schema.enum.join(' | ');
+    }
+    return el('code', text);
+  }
+
+  function table(headers, rows) {
+    const t = el('table');
+    const head = t.insertRow();
+    headers.forEach((h) => head.appendChild(el('th', h)));
+    rows.forEach((cells) => {
+      const row = t.insertRow();
+      cells.forEach((cell) => {
+        const td = row.insertCell();
+        td.appendChild(typeof cell === 'string' ? document.createTextNode(cell) : cell);
+      });
+    });
+    return t;
+  }
+
+  function contentTypes(content) {
+    return Object.keys(content || {}).join(', ');
+  }
+
+  function renderOperation(path, method, op) {
+    const section = el('section', undefined, 'operation');
+    const title = el('h3');
+    title.appendChild(el('span', method.toUpperCase(), 'method method-' + method));
+    title.appendChild(el('code', path));
+    section.appendChild(title);
+    if (op.summary) {
+      section.appendChild(el('p', op.summary));
+    }
+    if (op.description) {
+      section.appendChild(el('p', op.description, 'description'));
+    }
+
+    const params = (op.parameters || []).map(resolve);
+    if (params.length) {
+      section.appendChild(el('h4', '参数'));
+      section.appendChild(table(['名称', '位置', '类型', '必填', '说明'], params.map((p) => [
+        p.name, p.in, schemaNode(p.schema), p.required ? '是' : '', p.description || '',
+      ])));
+    }
+
+    if (op.requestBody) {
+      const body = resolve(op.requestBody);
+      section.appendChild(el('h4', '请求体'));
+      section.appendChild(table(['Content-Type', '结构', '说明'], Object.entries(body.content || {}).map(([type, media]) => [
+        type, schemaNode(media.schema), body.description || '',
+      ])));
+    }
+
+    const responses = Object.entries(op.responses || {});
+    if (responses.length) {
+      section.appendChild(el('h4', '响应'));
+      section.appendChild(table(['状态码', '说明', 'Content-Type', '结构'], responses.map(([status, raw]) => {
+        const resp = resolve(raw);
+        const media = Object.values(resp.content || {})[0] || {};
+        return [status, resp.description || '', contentTypes(resp.content), schemaNode(media.schema)];
+      })));
+    }
+    return section;
+  }
+
+  function renderSchema(name, raw) {
+    const schema = resolve(raw);
+    const section = el('section', undefined, 'schema');
+    section.id = 'schema-' + name;
+    section.appendChild(el('h3', name));
+    if (schema.description) {
+      section.appendChild(el('p', schema.description, 'description'));
+    }
+    const required = new Set(schema.required || []);
+    const props = Object.entries(schema.properties || {});
+    if (props.length) {
+      section.appendChild(table(['字段', '类型', '必填', '说明'], props.map(([prop, s]) => [
+        prop, schemaNode(s), required.has(prop) ? '是' : '', resolve(s).description || '',
+      ])));
+    } else {
+      section.appendChild(schemaNode(schema));
+    }
+    return section;
+  }
+
+  function render() {
+    const info = spec.info || {};
+    root.replaceChildren();
+    root.appendChild(el('h1', (info.title || 'API') + ' ' + (info.version || '')));
+    if (info.description) {
+      root.appendChild(el('p', info.description, 'description'));
+    }
+
+    // 按标签分组，未标注的接口归入“其他”
+    const groups = new Map((spec.tags || []).map((tag) => [tag.name, { tag, ops: [] }]));
+    Object.entries(spec.paths || {}).forEach(([path, item]) => {
+      ['get', 'post', 'put', 'patch', 'delete', 'options'].forEach((method) => {
+        const op = item[method];
+        if (!op) {
+          return;
+        }
+        const name = (op.tags && op.tags[0]) || '其他';
+        if (!groups.has(name)) {
+          groups.set(name, { tag: { name }, ops: [] });
+        }
+        groups.get(name).ops.push([path, method, op]);
+      });
+    });
+    groups.forEach(({ tag, ops }) => {
+      if (!ops.length) {
+        return;
+      }
+      root.appendChild(el('h2', tag.name));
+      if (tag.description) {
+        root.appendChild(el('p', tag.description, 'note'));
+      }
+      ops.forEach(([path, method, op]) => root.appendChild(renderOperation(path, method, op)));
+    });
+
+    const schemas = Object.entries((spec.components || {}).schemas || {});
+    if (schemas.length) {
+      root.appendChild(el('h2', '数据结构'));
+      schemas.forEach(([name, schema]) => root.appendChild(renderSchema(name, schema)));
+    }
+  }
+
+  fetch('openapi.json')
+    .then((resp) => {
+      if (!resp.ok) {
+        throw new Error(resp.status + ' ' + resp.statusText);
+      }
+      return resp.json();
+    })
+    .then((data) => {
+      spec = data;
+      render();
+    })
+    .catch((err) => {
+      root.replaceChildren(el('p', '加载 openapi.json 失败: ' + err.message, 'note'));
+    });
+})();
@@ -2,6 +2,7 @@ package main
 
 import (
     "context"
+    "flag"
     "log"
     "net/http"
     "os"
@@ -15,42 +16,110 @@ import (
 )
 
 func main() {
-    cfg, err := config.Load()
+    if len(os.Args) > 1 {
+        if cmd, ok := commands[os.Args[1]]; ok {
+            if err := cmd(os.Args[2:]); err != nil {
+                log.Fatalf("执行 %s 失败: %v", os.Args[1], err)
+            }
+            return
+        }
+    }
+
+    configPath := flag.String("config", "", "配置文件路径（YAML 或 TOML），也可通过 IP_API_CONFIG 指定")
+    flag.Parse()
+
+    cfg, err := config.Load(*configPath)
     if err != nil {
         log.Fatalf("配置加载失败: %v", err)
     }
 
-    svc, err := ipdb.NewService(cfg.QQWryPath)
+    svc, err := ipdb.NewService(cfg.Data.Path, ipdb.WithSkipNonPublic(cfg.Data.SkipNonPublic))
     if err != nil {
         log.Fatalf("初始化qqwry服务失败: %v", err)
     }
 
-    router := server.NewRouter(svc)
+    opts := server.Options{
+        TrustedProxies: cfg.Server.TrustedProxies,
+        BatchLimit:     cfg.Server.BatchLimit,
+        CIDRLimit:      cfg.Server.CIDRLimit,
+        Compat:         cfg.Server.Compat,
+        WebDir:         cfg.Server.WebDir,
+    }
+    if cfg.RateLimit.Enabled {
+        limiter, err := server.NewRateLimiter(server.RateLimitOptions{
+            Rate:       cfg.RateLimit.Rate,
+            Burst:      cfg.RateLimit.Burst,
+            DailyQuota: cfg.RateLimit.DailyQuota,
+            QuotaFile:  cfg.RateLimit.QuotaFile,
+        })
+        if err != nil {
+            log.Fatalf("初始化限流失败: %v", err)
+        }
+        defer func() {
+            if err := limiter.Close(); err != nil {
+                log.Printf("保存配额计数失败: %v", err)
+            }
+        }()
+        opts.RateLimiter = limiter
+    }
+
+    if cfg.CORS.Enabled {
+        opts.CORS = &server.CORSOptions{
+            AllowOrigins:     cfg.CORS.AllowOrigins,
+            AllowMethods:     cfg.CORS.AllowMethods,
+            AllowHeaders:     cfg.CORS.AllowHeaders,
+            ExposeHeaders:    cfg.CORS.ExposeHeaders,
+            AllowCredentials: cfg.CORS.AllowCredentials,
+            MaxAge:           time.Duration(cfg.CORS.MaxAge),
+            JSONP:            cfg.CORS.JSONP,
+        }
+    }
+    if cfg.Auth.Enabled {
+        store, err := server.LoadKeyStore(cfg.Auth.KeyFile)
+        if err != nil {
+            log.Fatalf("加载密钥库失败: %v", err)
+        }
+        log.Printf("已启用 API Key 认证，密钥数: %d，匿名访问: %t", len(store.Keys()), cfg.Auth.AllowAnonymous)
+        opts.Auth = server.NewAuthenticator(store, cfg.Auth.AllowAnonymous)
+    }
+
+    router, err := server.NewRouter(svc, opts)
+    if err != nil {
+        log.Fatalf("初始化路由失败: %v", err)
+    }
 
     srv := &http.Server{
-        Addr:              cfg.ListenAddr,
+        Addr:              cfg.Server.Listen,
         Handler:           router,
-        ReadHeaderTimeout: 5 * time.Second,
-        ReadTimeout:       10 * time.Second,
-        WriteTimeout:      15 * time.Second,
-        IdleTimeout:       60 * time.Second,
+        ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+        ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+        WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+        IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
     }
 
     // 启动 HTTP 服务
-    log.Printf("服务启动，监听地址: %s，数据源: %s", cfg.ListenAddr, cfg.QQWryPath)
+    log.Printf("服务启动，监听地址: %s，数据源: %s", cfg.Server.Listen, cfg.Data.Path)
     go func() {
         if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
             log.Fatalf("服务运行异常: %v", err)
         }
     }()
 
+    // 按配置周期检查远端数据更新，有变化时热加载
+    updateCtx, stopUpdate := context.WithCancel(context.Background())
+    defer stopUpdate()
+    if cfg.Update.Interval > 0 {
+        go runUpdater(updateCtx, cfg, svc)
+    }
+
     // 监听系统信号，执行优雅关停
     stop := make(chan os.Signal, 1)
     signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
     <-stop
+    stopUpdate()
 
     log.Printf("接收到退出信号，开始优雅关停...")
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
     defer cancel()
     if err := srv.Shutdown(ctx); err != nil {
         log.Printf("优雅关停失败，强制退出: %v", err)
@@ -59,4 +128,32 @@ func main() {
         }
     }
     log.Printf("服务已关闭")
-}
\ No newline at end of file
+}
+
+// runUpdater 定期通过条件请求检查数据文件，下载成功后重新加载服务数据。
+func runUpdater(ctx context.Context, cfg *config.Config, svc *ipdb.Service) {
+    ticker := time.NewTicker(time.Duration(cfg.Update.Interval))
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+        changed, err := cfg.UpdateData(ctx)
+        if err != nil {
+            if ctx.Err() == nil {
+                log.Printf("检查数据更新失败: %v", err)
+            }
+            continue
+        }
+        if !changed {
+            continue
+        }
+        if err := svc.Reload(); err != nil {
+            log.Printf("重新加载数据失败: %v", err)
+            continue
+        }
+        log.Printf("数据已热更新: %s", cfg.Data.Path)
+    }
+}
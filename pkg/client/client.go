@@ -0,0 +1,298 @@
+// Package client 是 IP 查询服务的 Go 客户端，封装单个查询、批量查询与数据元信息接口，
+// 内置超时重试与指数退避，错误可通过 errors.Is 与 ErrNotFound 等哨兵错误比较。
+//
+// 单元测试中可使用 clienttest 包提供的模拟服务端，无需启动真实服务。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认配置，与服务端默认值保持一致。
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultRetries    = 2
+	DefaultMinBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff = 5 * time.Second
+	DefaultBatchSize  = 100 // 服务端 server.batch_limit 的默认值
+)
+
+// Client 为 IP 查询服务的客户端，可被多个 goroutine 并发使用。
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	lang       string
+	userAgent  string
+
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	batchSize  int
+}
+
+// Option 用于定制 Client 的行为。
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client，例如配置代理或 TLS。
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAPIKey 设置请求携带的 API Key（X-API-Key 请求头）。
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithLanguage 设置展示语言（如 "en"、"zh-CN"），影响 country_name 等字段与错误提示的语言。
+func WithLanguage(lang string) Option {
+	return func(c *Client) {
+		c.lang = lang
+	}
+}
+
+// WithUserAgent 设置 User-Agent 请求头。
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithRetry 设置失败后的最大重试次数与退避区间；retries 为 0 时不重试。
+// 仅对网络错误、429 与 5xx 响应重试，服务端返回 Retry-After 时优先按其等待。
+func WithRetry(retries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithBatchSize 设置 LookupAll 单次请求的 IP 数量，需不大于服务端的 server.batch_limit。
+func WithBatchSize(n int) Option {
+	return func(c *Client) {
+		c.batchSize = n
+	}
+}
+
+// New 创建客户端，baseURL 为服务地址，如 http://localhost:8080。
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("服务地址不合法: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("服务地址不合法: %q", baseURL)
+	}
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		retries:    DefaultRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		batchSize:  DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retries < 0 {
+		c.retries = 0
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = DefaultBatchSize
+	}
+	return c, nil
+}
+
+// Lookup 查询指定 IPv4 的归属信息（GET /ip/{ip}）。
+func (c *Client) Lookup(ctx context.Context, ip string) (Result, error) {
+	var result Result
+	err := c.do(ctx, http.MethodGet, "/ip/"+url.PathEscape(ip), nil, &result)
+	return result, err
+}
+
+// LookupSelf 查询调用方自身出口 IP 的归属信息（GET /ip）。
+func (c *Client) LookupSelf(ctx context.Context) (Result, error) {
+	var result Result
+	err := c.do(ctx, http.MethodGet, "/ip", nil, &result)
+	return result, err
+}
+
+// LookupBatch 在一次请求中查询多个 IP（POST /ip/batch），数量不能超过服务端的 server.batch_limit。
+// 单个 IP 查询失败不影响其他结果，失败原因记录在对应 BatchItem 的 Err 中。
+func (c *Client) LookupBatch(ctx context.Context, ips []string) ([]BatchItem, error) {
+	var resp struct {
+		Results []struct {
+			IP     string  `json:"ip"`
+			Result *Result `json:"result"`
+			Code   string  `json:"code"`
+			Error  string  `json:"error"`
+		} `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/ip/batch", map[string][]string{"ips": ips}, &resp); err != nil {
+		return nil, err
+	}
+	items := make([]BatchItem, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		item := BatchItem{IP: r.IP, Result: r.Result}
+		if r.Code != "" {
+			item.Err = &Error{Code: r.Code, Message: r.Error}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// LookupAll 按批量大小（见 WithBatchSize）拆分请求查询任意数量的 IP，结果与 ips 顺序一致；
+// 任一批次请求失败时返回已完成的结果与该错误。
+func (c *Client) LookupAll(ctx context.Context, ips []string) ([]BatchItem, error) {
+	items := make([]BatchItem, 0, len(ips))
+	for start := 0; start < len(ips); start += c.batchSize {
+		end := min(start+c.batchSize, len(ips))
+		batch, err := c.LookupBatch(ctx, ips[start:end])
+		if err != nil {
+			return items, err
+		}
+		items = append(items, batch...)
+	}
+	return items, nil
+}
+
+// Metadata 返回服务端当前加载的数据文件信息（GET /meta）。
+func (c *Client) Metadata(ctx context.Context) (Metadata, error) {
+	var meta Metadata
+	err := c.do(ctx, http.MethodGet, "/meta", nil, &meta)
+	return meta, err
+}
+
+// do 发送请求并解析 JSON 响应，按配置对可重试的失败进行退避重试；path 为已转义的路径。
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, payload, out)
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			return err
+		}
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			wait = min(retryAfter, c.maxBackoff)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send 发送单次请求，返回服务端建议的重试等待时间（Retry-After）与错误。
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, out any) (time.Duration, error) {
+	u := *c.baseURL
+	// 同时设置 RawPath 与 Path，避免 String 对已转义的 path 再次转义
+	u.RawPath = u.EscapedPath() + path
+	var err error
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return 0, err
+	}
+	if c.lang != "" {
+		u.RawQuery = url.Values{"lang": {c.lang}}.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, fmt.Errorf("解析响应失败: %w", err)
+		}
+		return 0, nil
+	}
+	return parseRetryAfter(resp.Header.Get("Retry-After")), decodeError(resp)
+}
+
+// retryable 判断错误是否值得重试：网络错误、限流（429，每日配额耗尽除外）与 5xx 响应；调用方取消或超时不重试。
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			return apiErr.Code != "quota_exceeded"
+		}
+		return apiErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// backoff 返回第 attempt 次重试前的等待时间：指数增长并叠加随机抖动，避免大量客户端同时重试。
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.minBackoff << attempt
+	if wait <= 0 || wait > c.maxBackoff {
+		wait = c.maxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ipservice/pkg/client"
+	"ipservice/pkg/client/clienttest"
+)
+
+func TestLookup(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.SetResult(client.Result{IP: "8.8.8.8", Country: "美国", Area: "谷歌公司", Scope: "public"})
+
+	r, err := srv.Client().Lookup(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.IP != "8.8.8.8" || r.Country != "美国" || r.Area != "谷歌公司" {
+		t.Fatalf("Lookup = %+v", r)
+	}
+}
+
+// TestLookupEscapesPath 确认路径参数只转义一次，服务端收到的值与调用方传入的一致，且服务地址中的路径前缀得以保留。
+func TestLookupEscapesPath(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/ip/{ip}", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(client.Result{IP: r.PathValue("ip")})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, err := client.New(srv.URL + "/api/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"8.8.8.8", "fe80::1%eth0", "a b", "1.2.3.4/24", "100%"} {
+		r, err := c.Lookup(context.Background(), ip)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", ip, err)
+		}
+		if r.IP != ip {
+			t.Errorf("Lookup(%q): 服务端收到 %q", ip, r.IP)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.SetResult(client.Result{IP: "8.8.8.8", Country: "美国", Scope: "public"})
+	srv.FailNext(
+		clienttest.Failure{Status: http.StatusServiceUnavailable, Code: "unavailable"},
+		clienttest.Failure{Status: http.StatusTooManyRequests, Code: "rate_limited"},
+	)
+
+	r, err := srv.Client().Lookup(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Country != "美国" {
+		t.Fatalf("Lookup = %+v", r)
+	}
+	if n := srv.Requests(); n != 3 {
+		t.Fatalf("请求次数 = %d，期望 3", n)
+	}
+}
+
+// TestRetryGivesUp 确认重试次数用尽后返回最后一次的错误。
+func TestRetryGivesUp(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	for i := 0; i <= client.DefaultRetries; i++ {
+		srv.FailNext(clienttest.Failure{Status: http.StatusBadGateway, Code: "bad_gateway"})
+	}
+
+	_, err := srv.Client().Lookup(context.Background(), "8.8.8.8")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v，期望 502 错误", err)
+	}
+	if n := srv.Requests(); n != client.DefaultRetries+1 {
+		t.Fatalf("请求次数 = %d，期望 %d", n, client.DefaultRetries+1)
+	}
+}
+
+// TestRetryAfterCapped 确认按 Retry-After 等待时不超过最大退避时间。
+func TestRetryAfterCapped(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.FailNext(clienttest.Failure{Status: http.StatusTooManyRequests, Code: "rate_limited", RetryAfter: 30})
+
+	c := srv.Client(client.WithRetry(1, time.Millisecond, 20*time.Millisecond))
+	start := time.Now()
+	if _, err := c.Metadata(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("重试等待 %v，未受最大退避时间限制", elapsed)
+	}
+	if n := srv.Requests(); n != 2 {
+		t.Fatalf("请求次数 = %d，期望 2", n)
+	}
+}
+
+// TestRetryCanceled 确认退避等待期间取消 context 时立即返回。
+func TestRetryCanceled(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.FailNext(clienttest.Failure{Status: http.StatusServiceUnavailable, Code: "unavailable", RetryAfter: 30})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c := srv.Client(client.WithRetry(1, time.Minute, time.Minute))
+	if _, err := c.Metadata(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v，期望 context.DeadlineExceeded", err)
+	}
+	if n := srv.Requests(); n != 1 {
+		t.Fatalf("请求次数 = %d，期望 1", n)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure clienttest.Failure
+		want    error
+	}{
+		{"quota_exceeded", clienttest.Failure{Status: http.StatusTooManyRequests, Code: "quota_exceeded"}, client.ErrRateLimited},
+		{"not_found", clienttest.Failure{Status: http.StatusNotFound, Code: "not_found"}, client.ErrNotFound},
+		{"invalid_api_key", clienttest.Failure{Status: http.StatusUnauthorized, Code: "invalid_api_key"}, client.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := clienttest.NewServer()
+			defer srv.Close()
+			srv.FailNext(tt.failure)
+
+			if _, err := srv.Client().Metadata(context.Background()); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v，期望 %v", err, tt.want)
+			}
+			if n := srv.Requests(); n != 1 {
+				t.Fatalf("请求次数 = %d，期望 1", n)
+			}
+		})
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+
+	tests := []struct {
+		ip     string
+		want   error
+		status int
+		code   string
+	}{
+		{"bad", client.ErrInvalidIP, http.StatusBadRequest, "invalid_ip"},
+		{"2001:db8::1", client.ErrIPv6NotSupported, http.StatusBadRequest, "ipv6_not_supported"},
+		{"8.8.8.8", client.ErrNotFound, http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		_, err := c.Lookup(context.Background(), tt.ip)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Lookup(%q) err = %v，期望 %v", tt.ip, err, tt.want)
+			continue
+		}
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) {
+			t.Errorf("Lookup(%q) err = %T，期望 *client.Error", tt.ip, err)
+			continue
+		}
+		if apiErr.StatusCode != tt.status || apiErr.Code != tt.code || apiErr.RequestID == "" {
+			t.Errorf("Lookup(%q) err = %+v，期望状态 %d、错误码 %s 与请求 ID", tt.ip, apiErr, tt.status, tt.code)
+		}
+	}
+
+	// 未知错误码不对应任何哨兵错误
+	srv.FailNext(clienttest.Failure{Status: http.StatusBadRequest, Code: "something_new"})
+	_, err := c.Metadata(context.Background())
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "something_new" || errors.Unwrap(err) != nil {
+		t.Fatalf("err = %v，期望不映射哨兵错误的 *client.Error", err)
+	}
+}
+
+func TestLookupAll(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.SetBatchLimit(2)
+	srv.SetResult(client.Result{IP: "8.8.8.8", Country: "美国", Scope: "public"})
+	srv.SetResult(client.Result{IP: "1.1.1.1", Country: "澳大利亚", Scope: "public"})
+
+	ips := []string{"8.8.8.8", "bad", "1.1.1.1", "10.0.0.1", "9.9.9.9"}
+	items, err := srv.Client(client.WithBatchSize(2)).LookupAll(context.Background(), ips)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests(); n != 3 {
+		t.Fatalf("请求次数 = %d，期望按每批 2 个拆分为 3 次", n)
+	}
+	if len(items) != len(ips) {
+		t.Fatalf("结果数 = %d，期望 %d", len(items), len(ips))
+	}
+	for i, item := range items {
+		if item.IP != ips[i] {
+			t.Errorf("items[%d].IP = %q，期望 %q", i, item.IP, ips[i])
+		}
+	}
+	if items[0].Err != nil || items[0].Result.Country != "美国" {
+		t.Errorf("items[0] = %+v", items[0])
+	}
+	if !errors.Is(items[1].Err, client.ErrInvalidIP) || items[1].Result != nil {
+		t.Errorf("items[1] = %+v，期望 ErrInvalidIP", items[1])
+	}
+	if items[3].Err != nil || items[3].Result.Scope != "private" {
+		t.Errorf("items[3] = %+v，期望私有地址", items[3])
+	}
+	if !errors.Is(items[4].Err, client.ErrNotFound) {
+		t.Errorf("items[4] = %+v，期望 ErrNotFound", items[4])
+	}
+}
+
+// TestLookupAllBatchTooLarge 确认批量大小超过服务端上限时返回 batch_too_large 错误。
+func TestLookupAllBatchTooLarge(t *testing.T) {
+	srv := clienttest.NewServer()
+	defer srv.Close()
+	srv.SetBatchLimit(2)
+
+	items, err := srv.Client(client.WithBatchSize(3)).LookupAll(context.Background(), []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "batch_too_large" || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v，期望 batch_too_large", err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("items = %+v，期望为空", items)
+	}
+}
@@ -0,0 +1,294 @@
+// Package clienttest 提供 IP 查询服务的模拟服务端，供使用 client 包的代码编写单元测试，无需加载 qqwry.dat。
+//
+//	srv := clienttest.NewServer()
+//	defer srv.Close()
+//	srv.SetResult(client.Result{IP: "8.8.8.8", Country: "美国", Area: "谷歌公司", Scope: "public"})
+//	c := srv.Client()
+//	r, err := c.Lookup(ctx, "8.8.8.8")
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"ipservice/pkg/client"
+)
+
+// DefaultSelfIP 为 GET /ip 默认返回的调用方地址（TEST-NET-3 文档地址）。
+const DefaultSelfIP = "203.0.113.1"
+
+// Server 为模拟服务端，实现 /ip、/ip/{ip}、POST /ip、/ip/batch 与 /meta 接口，
+// 响应与错误格式与真实服务一致。未通过 SetResult 登记的公网地址返回 not_found。
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	results    map[string]client.Result
+	selfIP     string
+	meta       client.Metadata
+	batchLimit int
+	failures   []Failure
+	requests   int
+}
+
+// Failure 描述一次注入的失败响应。
+type Failure struct {
+	Status     int    // HTTP 状态码
+	Code       string // 错误码，如 rate_limited
+	RetryAfter int    // 大于 0 时输出 Retry-After 响应头（秒）
+}
+
+// NewServer 启动模拟服务端，使用完毕后需调用 Close。
+func NewServer() *Server {
+	s := &Server{
+		results:    make(map[string]client.Result),
+		selfIP:     DefaultSelfIP,
+		meta:       client.Metadata{Version: "clienttest", LoadedAt: time.Now()},
+		batchLimit: client.DefaultBatchSize,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ip", s.lookupSelf)
+	mux.HandleFunc("GET /ip/{ip}", s.lookupPath)
+	mux.HandleFunc("POST /ip", s.lookupBody)
+	mux.HandleFunc("POST /ip/batch", s.lookupBatch)
+	mux.HandleFunc("GET /meta", s.metadata)
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// Client 返回指向模拟服务端的客户端；重试退避缩短为毫秒级，opts 可覆盖默认设置。
+func (s *Server) Client(opts ...client.Option) *client.Client {
+	opts = append([]client.Option{client.WithRetry(client.DefaultRetries, time.Millisecond, 10*time.Millisecond)}, opts...)
+	c, err := client.New(s.URL, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// SetResult 登记 r.IP 的查询结果，重复登记时覆盖。
+func (s *Server) SetResult(r client.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[r.IP] = r
+}
+
+// SetSelfIP 设置 GET /ip 视为调用方的地址。
+func (s *Server) SetSelfIP(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.selfIP = ip
+}
+
+// SetMetadata 设置 /meta 返回的数据文件信息，Records 为 0 时按已登记的结果数量返回。
+func (s *Server) SetMetadata(m client.Metadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta = m
+}
+
+// SetBatchLimit 设置批量查询单次最多 IP 数，超出时返回 batch_too_large。
+func (s *Server) SetBatchLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batchLimit = n
+}
+
+// FailNext 使后续请求依次返回注入的失败响应，用于验证重试与错误处理。
+func (s *Server) FailNext(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failures...)
+}
+
+// Requests 返回服务端收到的请求数，包括注入失败的请求。
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// intercept 统计请求数，并在存在注入的失败时直接返回错误响应。
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var f *Failure
+		if len(s.failures) > 0 {
+			f = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if f != nil {
+			if f.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+			}
+			writeProblem(w, r, f.Status, f.Code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) lookupSelf(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ip := s.selfIP
+	s.mu.Unlock()
+	s.respondLookup(w, r, ip)
+}
+
+func (s *Server) lookupPath(w http.ResponseWriter, r *http.Request) {
+	s.respondLookup(w, r, r.PathValue("ip"))
+}
+
+func (s *Server) lookupBody(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IP string `json:"ip"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+	s.respondLookup(w, r, req.IP)
+}
+
+func (s *Server) respondLookup(w http.ResponseWriter, r *http.Request, ip string) {
+	result, status, code := s.lookup(ip)
+	if code != "" {
+		writeProblem(w, r, status, code)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) lookupBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IPs []string `json:"ips"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IPs == nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+	s.mu.Lock()
+	limit := s.batchLimit
+	s.mu.Unlock()
+	if len(req.IPs) > limit {
+		writeProblem(w, r, http.StatusBadRequest, "batch_too_large")
+		return
+	}
+
+	type item struct {
+		IP     string         `json:"ip"`
+		Result *client.Result `json:"result,omitempty"`
+		Code   string         `json:"code,omitempty"`
+		Error  string         `json:"error,omitempty"`
+	}
+	items := make([]item, 0, len(req.IPs))
+	for _, ip := range req.IPs {
+		it := item{IP: ip}
+		if result, _, code := s.lookup(ip); code != "" {
+			it.Code, it.Error = code, code
+		} else {
+			it.Result = &result
+		}
+		items = append(items, it)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"total": len(items), "results": items})
+}
+
+func (s *Server) metadata(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	meta := s.meta
+	if meta.Records == 0 {
+		meta.Records = len(s.results)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, meta)
+}
+
+// lookup 按真实服务的规则校验地址并返回登记的结果：非法地址为 invalid_ip，IPv6 为 ipv6_not_supported，
+// 未登记的非公网地址仅返回 scope，未登记的公网地址为 not_found。
+func (s *Server) lookup(ip string) (client.Result, int, string) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return client.Result{}, http.StatusBadRequest, "invalid_ip"
+	}
+	if !addr.Unmap().Is4() {
+		return client.Result{}, http.StatusBadRequest, "ipv6_not_supported"
+	}
+
+	s.mu.Lock()
+	result, ok := s.results[ip]
+	s.mu.Unlock()
+	if ok {
+		return result, 0, ""
+	}
+	if scope := scopeOf(addr.Unmap()); scope != "public" {
+		return client.Result{IP: ip, Scope: scope, Raw: []string{"", ""}}, 0, ""
+	}
+	return client.Result{}, http.StatusNotFound, "not_found"
+}
+
+// 测试中常用的特殊用途网段，完整划分见服务端的 ipdb.ClassifyScope。
+var (
+	cgnatPrefix    = netip.MustParsePrefix("100.64.0.0/10")
+	reservedPrefix = []netip.Prefix{
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("198.51.100.0/24"),
+		netip.MustParsePrefix("203.0.113.0/24"),
+		netip.MustParsePrefix("240.0.0.0/4"),
+	}
+)
+
+// scopeOf 粗略划分地址范围，覆盖测试中常用的私有、回环、链路本地与文档地址。
+func scopeOf(addr netip.Addr) string {
+	for _, p := range reservedPrefix {
+		if p.Contains(addr) {
+			return "reserved"
+		}
+	}
+	switch {
+	case cgnatPrefix.Contains(addr):
+		return "cgnat"
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsPrivate():
+		return "private"
+	case addr.IsLinkLocalUnicast():
+		return "link-local"
+	case addr.IsMulticast():
+		return "multicast"
+	}
+	return "public"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeProblem 输出与真实服务一致的 RFC 7807 错误响应，message 直接使用错误码。
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code string) {
+	requestID := fmt.Sprintf("clienttest-%d", time.Now().UnixNano())
+	w.Header().Set("X-Request-ID", requestID)
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type":       "about:blank",
+		"title":      http.StatusText(status),
+		"status":     status,
+		"detail":     code,
+		"instance":   r.URL.Path,
+		"code":       code,
+		"message":    code,
+		"request_id": requestID,
+	})
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 哨兵错误，与服务端 ipdb 包的领域错误一一对应，可通过 errors.Is 判定。
+var (
+	ErrInvalidIP        = errors.New("invalid ip")
+	ErrIPv6NotSupported = errors.New("ipv6 not supported")
+	ErrNotFound         = errors.New("ip not found")
+	ErrDecodeCountry    = errors.New("decode country failed")
+	ErrDecodeArea       = errors.New("decode area failed")
+	ErrInvalidQuery     = errors.New("invalid query")
+	ErrInvalidCIDR      = errors.New("invalid cidr")
+	ErrCorruptData      = errors.New("corrupt data")
+)
+
+// 认证与限流相关的哨兵错误。
+var (
+	ErrUnauthorized = errors.New("unauthorized")       // 缺少、无效或过期的 API Key
+	ErrForbidden    = errors.New("insufficient scope") // API Key 权限不足
+	ErrRateLimited  = errors.New("rate limited")       // 触发限流或每日配额
+)
+
+// codeErrors 将服务端错误码映射为哨兵错误。
+var codeErrors = map[string]error{
+	"invalid_ip":            ErrInvalidIP,
+	"client_ipv6":           ErrIPv6NotSupported,
+	"ipv6_not_supported":    ErrIPv6NotSupported,
+	"not_found":             ErrNotFound,
+	"decode_country_failed": ErrDecodeCountry,
+	"decode_area_failed":    ErrDecodeArea,
+	"invalid_query":         ErrInvalidQuery,
+	"invalid_cidr":          ErrInvalidCIDR,
+	"corrupt_data":          ErrCorruptData,
+	"missing_api_key":       ErrUnauthorized,
+	"invalid_api_key":       ErrUnauthorized,
+	"expired_api_key":       ErrUnauthorized,
+	"insufficient_scope":    ErrForbidden,
+	"rate_limited":          ErrRateLimited,
+	"quota_exceeded":        ErrRateLimited,
+}
+
+// Error 为服务端返回的错误（RFC 7807 problem 响应）。
+// 批量查询中单个 IP 的错误没有独立的 HTTP 状态，此时 StatusCode 为 0。
+type Error struct {
+	StatusCode int
+	Code       string // 服务端错误码，如 not_found
+	Message    string
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("ipservice: %s (%s)", e.Message, e.Code)
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("ipservice: %d %s (%s)", e.StatusCode, e.Message, e.Code)
+	}
+	if e.RequestID != "" {
+		msg += " request_id=" + e.RequestID
+	}
+	return msg
+}
+
+// Unwrap 返回错误码对应的哨兵错误，未知错误码返回 nil。
+func (e *Error) Unwrap() error {
+	return codeErrors[e.Code]
+}
+
+// decodeError 将非 2xx 响应解析为 *Error；响应体不是 problem 格式时以状态描述代替。
+func decodeError(resp *http.Response) error {
+	var p struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &p) != nil || p.Code == "" {
+		p.Message = http.StatusText(resp.StatusCode)
+	}
+	if p.RequestID == "" {
+		p.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	return &Error{StatusCode: resp.StatusCode, Code: p.Code, Message: p.Message, RequestID: p.RequestID}
+}
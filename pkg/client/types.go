@@ -0,0 +1,43 @@
+package client
+
+import "time"
+
+// Result 为单个 IP 的查询结果，字段与服务端 /ip 接口的 JSON 响应一致。
+type Result struct {
+	IP          string   `json:"ip"`
+	Country     string   `json:"country"`
+	Area        string   `json:"area"`
+	Scope       string   `json:"scope"` // public、private、loopback、cgnat、link-local、multicast、reserved
+	CountryCode string   `json:"country_code,omitempty"`
+	CountryName string   `json:"country_name,omitempty"`
+	Continent   string   `json:"continent,omitempty"`
+	Province    string   `json:"province,omitempty"`
+	City        string   `json:"city,omitempty"`
+	Adcode      string   `json:"adcode,omitempty"`
+	Lat         float64  `json:"lat,omitempty"`
+	Lon         float64  `json:"lon,omitempty"`
+	Range       *Range   `json:"range,omitempty"` // 服务端跳过非公网地址的查询时为 nil
+	Raw         []string `json:"raw"`
+}
+
+// Range 为命中的 qqwry 区段及其拆分后的 CIDR 前缀。
+type Range struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	CIDRs []string `json:"cidrs"`
+}
+
+// BatchItem 为批量查询中单个 IP 的结果，查询失败时 Result 为 nil，Err 为 *Error。
+type BatchItem struct {
+	IP     string
+	Result *Result
+	Err    error
+}
+
+// Metadata 为服务端当前加载的数据文件信息。
+type Metadata struct {
+	Version  string    `json:"version"`
+	Records  int       `json:"records"`
+	Size     int64     `json:"size"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
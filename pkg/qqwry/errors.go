@@ -0,0 +1,74 @@
+package qqwry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 查询与解析错误，可通过 errors.Is 判定。
+var (
+	ErrInvalidIP        = errors.New("invalid ip")
+	ErrIPv6NotSupported = errors.New("ipv6 not supported")
+	ErrNotFound         = errors.New("ip not found")
+	ErrDecodeCountry    = errors.New("decode country failed")
+	ErrDecodeArea       = errors.New("decode area failed")
+	ErrCorruptData      = errors.New("corrupt data")
+)
+
+// maxValidationIssues 限制单次校验收集的问题数量，避免严重损坏的文件产生海量输出。
+const maxValidationIssues = 20
+
+// CorruptError 描述数据文件中某一偏移处的结构错误，可通过 errors.Is 匹配 ErrCorruptData。
+type CorruptError struct {
+	Offset  uint32
+	Message string
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("偏移 0x%08x: %s", e.Offset, e.Message)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return ErrCorruptData
+}
+
+func corruptf(offset uint32, format string, args ...any) error {
+	return &CorruptError{Offset: offset, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidationError 汇总一次完整校验中发现的全部结构问题。
+type ValidationError struct {
+	Issues    []*CorruptError
+	Truncated bool
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "qqwry.dat 校验失败，共发现 %d 处问题", len(e.Issues))
+	if e.Truncated {
+		b.WriteString("（仅列出前若干项）")
+	}
+	for _, issue := range e.Issues {
+		b.WriteString("\n  - ")
+		b.WriteString(issue.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrCorruptData
+}
+
+func (e *ValidationError) add(err error) bool {
+	if len(e.Issues) >= maxValidationIssues {
+		e.Truncated = true
+		return false
+	}
+	var issue *CorruptError
+	if !errors.As(err, &issue) {
+		issue = &CorruptError{Message: err.Error()}
+	}
+	e.Issues = append(e.Issues, issue)
+	return true
+}
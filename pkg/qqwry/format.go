@@ -0,0 +1,226 @@
+package qqwry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+const (
+	headerLen     = 8
+	indexEntryLen = 7
+	redirectMode1 = 0x01
+	redirectMode2 = 0x02
+
+	// maxRedirectDepth 为单条记录允许的最大重定向层数，正常数据最多两层
+	maxRedirectDepth = 4
+)
+
+// parseHeader 解析文件头并校验索引区边界，返回索引区起始偏移与记录数。
+func parseHeader(data []byte) (indexStart, total uint32, err error) {
+	if len(data) < headerLen {
+		return 0, 0, corruptf(0, "文件长度 %d 字节，不足以容纳文件头", len(data))
+	}
+	indexStart = binary.LittleEndian.Uint32(data[:4])
+	indexEnd := binary.LittleEndian.Uint32(data[4:8])
+	switch {
+	case indexStart < headerLen:
+		return 0, 0, corruptf(0, "索引区起始偏移 0x%08x 与文件头重叠", indexStart)
+	case indexEnd < indexStart:
+		return 0, 0, corruptf(4, "索引区结束偏移 0x%08x 小于起始偏移 0x%08x", indexEnd, indexStart)
+	case (indexEnd-indexStart)%indexEntryLen != 0:
+		return 0, 0, corruptf(4, "索引区长度 %d 不是 %d 字节的整数倍", indexEnd-indexStart, indexEntryLen)
+	case uint64(indexEnd)+indexEntryLen > uint64(len(data)):
+		return 0, 0, corruptf(4, "索引区结束偏移 0x%08x 超出文件长度 %d", indexEnd, len(data))
+	}
+	return indexStart, (indexEnd-indexStart)/indexEntryLen + 1, nil
+}
+
+// locate 二分查找最后一个起始地址 <= target 的索引条目；不存在时返回 total。
+func (r *Reader) locate(target uint32) (uint32, error) {
+	lo, hi := uint32(0), r.total
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := r.entryStart(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start <= target {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo == 0 {
+		return r.total, nil
+	}
+	return lo - 1, nil
+}
+
+// entryStart 读取第 i 条索引的起始 IP。
+func (r *Reader) entryStart(i uint32) (uint32, error) {
+	offset := r.indexStart + i*indexEntryLen
+	if i >= r.total || uint64(offset)+indexEntryLen > uint64(len(r.data)) {
+		return 0, corruptf(offset, "第 %d 条索引越界", i)
+	}
+	return binary.LittleEndian.Uint32(r.data[offset : offset+4]), nil
+}
+
+// entry 读取第 i 条索引，返回区段起止 IP 与记录偏移。
+func (r *Reader) entry(i uint32) (start, end, recordOffset uint32, err error) {
+	start, err = r.entryStart(i)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	offset := r.indexStart + i*indexEntryLen
+	recordOffset = readUint24(r.data[offset+4 : offset+7])
+	if recordOffset < headerLen || uint64(recordOffset)+4 > uint64(r.indexStart) {
+		return 0, 0, 0, corruptf(offset+4, "第 %d 条索引的记录偏移 0x%06x 不在记录区内", i, recordOffset)
+	}
+	end = binary.LittleEndian.Uint32(r.data[recordOffset : recordOffset+4])
+	return start, end, recordOffset, nil
+}
+
+// readRecord 解析记录区中位于 offset 的记录（跳过 4 字节结束 IP），返回国家与区域字段。
+func (r *Reader) readRecord(offset uint32) ([]byte, []byte, error) {
+	return r.readLocation(offset+4, 0)
+}
+
+// readLocation 解析国家+区域字段；模式 1 重定向整条信息，模式 2 仅重定向国家字段。
+// depth 限制重定向层数，避免损坏文件中的循环重定向导致死循环。
+func (r *Reader) readLocation(offset uint32, depth int) ([]byte, []byte, error) {
+	if depth > maxRedirectDepth {
+		return nil, nil, corruptf(offset, "重定向层数超过 %d", maxRedirectDepth)
+	}
+	mode, err := r.byteAt(offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch mode {
+	case redirectMode1:
+		target, err := r.redirectTarget(offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		return r.readLocation(target, depth+1)
+	case redirectMode2:
+		target, err := r.redirectTarget(offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		country, _, err := r.readString(target, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		area, _, err := r.readString(offset+4, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return country, area, nil
+	default:
+		country, next, err := r.readCString(offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		area, _, err := r.readString(next, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return country, area, nil
+	}
+}
+
+// readString 读取可能经过重定向的单个字符串字段，返回字段内容与其后的偏移。
+func (r *Reader) readString(offset uint32, depth int) ([]byte, uint32, error) {
+	if depth > maxRedirectDepth {
+		return nil, offset, corruptf(offset, "重定向层数超过 %d", maxRedirectDepth)
+	}
+	mode, err := r.byteAt(offset)
+	if err != nil {
+		return nil, offset, err
+	}
+	if mode == redirectMode1 || mode == redirectMode2 {
+		if uint64(offset)+4 > uint64(r.indexStart) {
+			return nil, offset, corruptf(offset, "重定向字段被截断")
+		}
+		target := readUint24(r.data[offset+1 : offset+4])
+		if target == 0 {
+			// 部分记录以 0 偏移表示区域字段缺失
+			return nil, offset + 4, nil
+		}
+		value, _, err := r.readString(target, depth+1)
+		if err != nil {
+			return nil, offset, err
+		}
+		return value, offset + 4, nil
+	}
+	return r.readCString(offset)
+}
+
+// redirectTarget 读取 offset 处重定向字段（1 字节模式 + 3 字节偏移）的目标地址。
+func (r *Reader) redirectTarget(offset uint32) (uint32, error) {
+	if uint64(offset)+4 > uint64(r.indexStart) {
+		return 0, corruptf(offset, "重定向字段被截断")
+	}
+	target := readUint24(r.data[offset+1 : offset+4])
+	if target < headerLen || target >= r.indexStart {
+		return 0, corruptf(offset, "重定向目标 0x%06x 不在记录区内", target)
+	}
+	return target, nil
+}
+
+func (r *Reader) byteAt(offset uint32) (byte, error) {
+	if offset < headerLen || offset >= r.indexStart {
+		return 0, corruptf(offset, "偏移不在记录区内")
+	}
+	return r.data[offset], nil
+}
+
+// readCString 读取以 0 结尾的字符串，终止符必须位于记录区内。
+func (r *Reader) readCString(offset uint32) ([]byte, uint32, error) {
+	if offset < headerLen || offset >= r.indexStart {
+		return nil, offset, corruptf(offset, "字符串偏移不在记录区内")
+	}
+	region := r.data[offset:r.indexStart]
+	n := bytes.IndexByte(region, 0)
+	if n < 0 {
+		return nil, offset, corruptf(offset, "字符串缺少终止符")
+	}
+	return region[:n], offset + uint32(n) + 1, nil
+}
+
+func readUint24(buf []byte) uint32 {
+	if len(buf) < 3 {
+		return 0
+	}
+	var val uint32
+	val = uint32(buf[0])
+	val |= uint32(buf[1]) << 8
+	val |= uint32(buf[2]) << 16
+	return val
+}
+
+func decodeGBK(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", nil
+	}
+	reader := transform.NewReader(bytes.NewReader(b), simplifiedchinese.GBK.NewDecoder())
+	converted, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(converted), nil
+}
+
+// normalize 去除首尾空白，并将纯真数据中的占位文本“CZ88.NET”视为空值。
+func normalize(value string) string {
+	cleaned := strings.TrimSpace(value)
+	if cleaned == "" || strings.EqualFold(cleaned, "CZ88.NET") {
+		return ""
+	}
+	return cleaned
+}
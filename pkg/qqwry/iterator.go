@@ -0,0 +1,88 @@
+package qqwry
+
+// Iterator 按起始地址升序遍历区段记录，用法与 bufio.Scanner 相同：
+//
+//	it := r.Iterate()
+//	for it.Next() {
+//		rec := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Iterator 不可在多个 goroutine 间共享。
+type Iterator struct {
+	r           *Reader
+	next        uint32 // 下一条待读取的索引条目
+	first, last uint32 // 仅返回与闭区间 [first, last] 重叠的区段
+	record      Record
+	err         error
+}
+
+// Iterate 返回遍历全部记录的迭代器。
+func (r *Reader) Iterate() *Iterator {
+	return r.iterateFrom(0)
+}
+
+// IterateRange 返回遍历与闭区间 [first, last] 重叠的记录的迭代器，先二分定位首个重叠的条目再顺序读取。
+func (r *Reader) IterateRange(first, last uint32) *Iterator {
+	it := r.iterateFrom(0)
+	it.first, it.last = first, last
+	if first > last {
+		it.next = r.total
+		return it
+	}
+	// 最后一个起始地址 <= first 的条目要么覆盖 first，要么在其之前结束
+	i, err := r.locate(first)
+	if err != nil {
+		it.err = err
+		return it
+	}
+	if i < r.total {
+		it.next = i
+	}
+	return it
+}
+
+// iterateFrom 返回从第 first 条索引开始遍历的迭代器。
+func (r *Reader) iterateFrom(first uint32) *Iterator {
+	return &Iterator{r: r, next: first, last: ^uint32(0)}
+}
+
+// Next 读取下一条记录，遍历结束或出错时返回 false。
+func (it *Iterator) Next() bool {
+	for it.err == nil && it.next < it.r.total {
+		start, end, recordOffset, err := it.r.entry(it.next)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.next++
+		if start > it.last {
+			it.next = it.r.total
+			return false
+		}
+		if end < it.first {
+			continue
+		}
+		countryRaw, areaRaw, err := it.r.readRecord(recordOffset)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.record = Record{Start: start, End: end, Country: it.r.field(countryRaw), Area: it.r.field(areaRaw)}
+		return true
+	}
+	return false
+}
+
+// Record 返回最近一次 Next 读取的记录。
+func (it *Iterator) Record() Record {
+	return it.record
+}
+
+// Err 返回遍历过程中遇到的错误，正常结束时为 nil。
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,128 @@
+// Package qqwrytest 在内存中构造 qqwry.dat，供测试使用，无需下载真实数据文件。
+//
+//	data := qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据"))
+//	r, err := qqwry.FromBytes(data)
+//
+// 生成的文件会复用重复的国家与区域字段（模式 1、模式 2 重定向），覆盖真实数据中的各类记录格式。
+package qqwrytest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Record 为一条待写入的区段记录，Start、End 为点分十进制的闭区间起止地址。
+type Record struct {
+	Start   string
+	End     string
+	Country string
+	Area    string
+}
+
+// Sample 返回覆盖国内省市、境外、局域网等常见情形的样例记录，末尾附带版本为 version 的版本记录。
+func Sample(version string) []Record {
+	return []Record{
+		{"0.0.0.0", "0.255.255.255", "IANA保留地址", ""},
+		{"1.0.0.0", "1.0.0.255", "澳大利亚", "APNIC"},
+		{"1.0.1.0", "1.0.3.255", "福建省福州市", "电信"},
+		{"1.0.4.0", "1.1.255.255", "澳大利亚墨尔本", "CZ88.NET"},
+		{"1.2.0.0", "1.2.1.255", "江苏省南京市", "移动"},
+		{"1.2.2.0", "1.2.127.255", "江苏省苏州市", "移动"},
+		{"1.2.128.0", "1.2.255.255", "广东省深圳市", "电信"},
+		{"1.3.0.0", "1.3.255.255", "中国–内蒙古–呼和浩特", "联通"},
+		{"1.4.0.0", "1.4.255.255", "日本东京", "KDDI"},
+		{"1.5.0.0", "8.8.7.255", "美国", ""},
+		{"8.8.8.0", "8.8.8.255", "美国", "谷歌公司"},
+		{"8.8.9.0", "9.255.255.255", "美国", ""},
+		{"10.0.0.0", "10.255.255.255", "局域网", "对方和您在同一内部网"},
+		{"11.0.0.0", "58.215.255.255", "美国", ""},
+		{"58.216.0.0", "58.216.255.255", "江苏省常州市", "电信"},
+		{"58.217.0.0", "113.255.255.255", "香港", "电讯盈科"},
+		{"114.0.0.0", "114.114.114.255", "北京市", "联通"},
+		{"114.114.115.0", "126.255.255.255", "广西南宁市", "电信"},
+		{"127.0.0.0", "127.255.255.255", "本机地址", ""},
+		{"128.0.0.0", "223.255.255.255", "德国", "柏林"},
+		{"224.0.0.0", "255.255.254.255", "IANA", "保留地址"},
+		{"255.255.255.0", "255.255.255.255", "纯真网络", version},
+	}
+}
+
+// Build 按 qqwry.dat 格式编码 records，records 需按起始地址升序排列且互不重叠；
+// 地址或文本无法编码时 panic。
+func Build(records []Record) []byte {
+	buf := make([]byte, 8)
+	countryAt := make(map[string]uint32)
+	areaAt := make(map[string]uint32)
+	pairAt := make(map[string]uint32)
+	starts := make([]uint32, 0, len(records))
+	offsets := make([]uint32, 0, len(records))
+
+	writeArea := func(area string) {
+		if off, ok := areaAt[area]; ok {
+			buf = append(buf, 0x02)
+			buf = put24(buf, off)
+			return
+		}
+		areaAt[area] = uint32(len(buf))
+		buf = append(buf, gbk(area)...)
+		buf = append(buf, 0)
+	}
+	for _, r := range records {
+		starts = append(starts, ipv4(r.Start))
+		offsets = append(offsets, uint32(len(buf)))
+		buf = binary.LittleEndian.AppendUint32(buf, ipv4(r.End))
+
+		key := r.Country + "\x00" + r.Area
+		if off, ok := pairAt[key]; ok {
+			// 模式 1：国家与区域整体重定向
+			buf = append(buf, 0x01)
+			buf = put24(buf, off)
+			continue
+		}
+		if off, ok := countryAt[r.Country]; ok {
+			// 模式 2：仅重定向国家字段，区域紧随其后
+			buf = append(buf, 0x02)
+			buf = put24(buf, off)
+			writeArea(r.Area)
+			continue
+		}
+		pairAt[key] = uint32(len(buf))
+		countryAt[r.Country] = uint32(len(buf))
+		buf = append(buf, gbk(r.Country)...)
+		buf = append(buf, 0)
+		writeArea(r.Area)
+	}
+
+	indexStart := uint32(len(buf))
+	for i := range starts {
+		buf = binary.LittleEndian.AppendUint32(buf, starts[i])
+		buf = put24(buf, offsets[i])
+	}
+	binary.LittleEndian.PutUint32(buf[0:4], indexStart)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(buf))-7)
+	return buf
+}
+
+func ipv4(s string) uint32 {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || !addr.Is4() {
+		panic(fmt.Sprintf("qqwrytest: 地址不合法: %q", s))
+	}
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func gbk(s string) []byte {
+	b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		panic(fmt.Sprintf("qqwrytest: 无法编码为 GBK: %q", s))
+	}
+	return b
+}
+
+func put24(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16))
+}
@@ -0,0 +1,244 @@
+// Package qqwry 读取纯真 IP 数据库（qqwry.dat），提供按地址查询、顺序遍历与数据版本信息，
+// 不依赖 HTTP 框架，可直接在其他 Go 项目中使用。
+//
+//	r, err := qqwry.Open("data/qqwry.dat")
+//	if err != nil {
+//		return err
+//	}
+//	rec, err := r.Lookup("8.8.8.8")
+//	fmt.Println(rec.Country, rec.Area)
+//
+// 国家与区域字段已由 GBK 转为 UTF-8，数据中的占位文本“CZ88.NET”返回为空字符串。
+package qqwry
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Reader 为加载到内存中的 qqwry 数据，创建后只读，可被多个 goroutine 并发使用。
+type Reader struct {
+	data []byte
+
+	// indexStart 为索引区起始偏移，同时也是记录区的结束位置
+	indexStart uint32
+	total      uint32
+	meta       Metadata
+
+	// interned 为原始 GBK 字段到解码、归一化后字符串的驻留表，加载时构建，
+	// 查询时以 string(raw) 作键读取不会产生分配
+	interned map[string]string
+}
+
+// Record 为一条地址区段记录，Start、End 为闭区间的起止地址（大端序整数）。
+type Record struct {
+	Start   uint32
+	End     uint32
+	Country string
+	Area    string
+}
+
+// StartIP 返回区段起始地址的点分十进制形式。
+func (r Record) StartIP() string {
+	return FormatIPv4(r.Start)
+}
+
+// EndIP 返回区段结束地址的点分十进制形式。
+func (r Record) EndIP() string {
+	return FormatIPv4(r.End)
+}
+
+// Metadata 描述数据文件的版本与规模。
+type Metadata struct {
+	Version string // 数据版本描述，如“2024年10月16日IP数据”，无法识别时为空
+	Records int    // 地址区段数量
+	Size    int64  // 文件大小（字节）
+
+	// VersionRecord 为携带版本描述的记录（通常位于 255.255.255.0 之后），Version 为空时为零值
+	VersionRecord Record
+}
+
+// Open 从指定路径加载数据文件，并在加载时完成完整的结构校验。
+func Open(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取qqwry.dat失败: %w", err)
+	}
+	return FromBytes(data)
+}
+
+// NewReader 从 r 读取 size 字节的数据文件到内存并完成结构校验，之后不再访问 r。
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < 0 || size > int64(^uint32(0)) {
+		return nil, fmt.Errorf("数据文件大小 %d 不合法", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(r, 0, size), data); err != nil {
+		return nil, fmt.Errorf("读取qqwry.dat失败: %w", err)
+	}
+	return FromBytes(data)
+}
+
+// FromBytes 使用内存中的数据文件创建 Reader 并完成结构校验；data 会被直接引用，调用方之后不应再修改。
+func FromBytes(data []byte) (*Reader, error) {
+	indexStart, total, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{data: data, indexStart: indexStart, total: total}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	if err := r.intern(); err != nil {
+		return nil, err
+	}
+	if r.meta, err = r.readMetadata(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ValidateFile 加载并完整校验数据文件，常用于替换线上文件前的结构检查。
+func ValidateFile(path string) error {
+	_, err := Open(path)
+	return err
+}
+
+// ValidateBytes 对内存中的数据做完整结构校验。
+func ValidateBytes(data []byte) error {
+	indexStart, total, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	r := &Reader{data: data, indexStart: indexStart, total: total}
+	return r.validate()
+}
+
+// Lookup 查询点分十进制 IPv4 地址所在的区段。
+func (r *Reader) Lookup(ip string) (Record, error) {
+	target, err := ParseIPv4(ip)
+	if err != nil {
+		return Record{}, err
+	}
+	return r.LookupUint32(target)
+}
+
+// LookupAddr 查询 netip.Addr 形式的地址，IPv4 映射的 IPv6 地址按 IPv4 处理。
+func (r *Reader) LookupAddr(addr netip.Addr) (Record, error) {
+	target, err := AddrToUint32(addr)
+	if err != nil {
+		return Record{}, err
+	}
+	return r.LookupUint32(target)
+}
+
+// LookupUint32 查询大端序整数形式的 IPv4 地址所在的区段，未收录时返回 ErrNotFound。
+// 命中时不产生内存分配，返回的字符串均来自加载时构建的驻留表。
+func (r *Reader) LookupUint32(target uint32) (Record, error) {
+	i, err := r.locate(target)
+	if err != nil {
+		return Record{}, err
+	}
+	if i < r.total {
+		start, end, recordOffset, err := r.entry(i)
+		if err != nil {
+			return Record{}, err
+		}
+		if start <= target && target <= end {
+			countryRaw, areaRaw, err := r.readRecord(recordOffset)
+			if err != nil {
+				return Record{}, err
+			}
+			return Record{Start: start, End: end, Country: r.field(countryRaw), Area: r.field(areaRaw)}, nil
+		}
+	}
+	return Record{}, fmt.Errorf("%w: 未找到IP %s 的归属信息", ErrNotFound, FormatIPv4(target))
+}
+
+// Metadata 返回数据文件的版本与规模。
+func (r *Reader) Metadata() Metadata {
+	return r.meta
+}
+
+// readMetadata 从末尾的版本记录（如“纯真网络 / 2024年10月16日IP数据”）中提取数据版本。
+func (r *Reader) readMetadata() (Metadata, error) {
+	meta := Metadata{Records: int(r.total), Size: int64(len(r.data))}
+	it := r.iterateFrom(r.total - min(r.total, 3))
+	for it.Next() {
+		if rec := it.Record(); strings.Contains(rec.Country, "纯真网络") {
+			meta.Version = rec.Area
+			meta.VersionRecord = rec
+		}
+	}
+	return meta, it.Err()
+}
+
+// intern 遍历全部记录，将出现过的国家与区域字段各解码一次并登记到驻留表。
+func (r *Reader) intern() error {
+	r.interned = make(map[string]string)
+	for i := uint32(0); i < r.total; i++ {
+		_, _, recordOffset, err := r.entry(i)
+		if err != nil {
+			return err
+		}
+		countryRaw, areaRaw, err := r.readRecord(recordOffset)
+		if err != nil {
+			return err
+		}
+		if err := r.internField(countryRaw); err != nil {
+			return fmt.Errorf("%w: 国家字段编码转换失败: %v", ErrDecodeCountry, err)
+		}
+		if err := r.internField(areaRaw); err != nil {
+			return fmt.Errorf("%w: 区域字段编码转换失败: %v", ErrDecodeArea, err)
+		}
+	}
+	return nil
+}
+
+func (r *Reader) internField(raw []byte) error {
+	if _, ok := r.interned[string(raw)]; ok {
+		return nil
+	}
+	v, err := decodeGBK(raw)
+	if err != nil {
+		return err
+	}
+	r.interned[string(raw)] = normalize(v)
+	return nil
+}
+
+// field 返回原始字段对应的字符串；驻留表在加载时覆盖了全部字段，此处不会再解码。
+func (r *Reader) field(raw []byte) string {
+	return r.interned[string(raw)]
+}
+
+// ParseIPv4 将字符串解析为大端序的 IPv4 整数表示，IPv4 映射的 IPv6 地址按 IPv4 处理。
+func ParseIPv4(ipStr string) (uint32, error) {
+	addr, err := netip.ParseAddr(ipStr)
+	if err != nil || addr.Zone() != "" {
+		return 0, fmt.Errorf("%w: 无法解析IP: %s", ErrInvalidIP, ipStr)
+	}
+	return AddrToUint32(addr)
+}
+
+// AddrToUint32 将 netip.Addr 转换为大端序的 IPv4 整数表示，IPv4 映射的 IPv6 地址按 IPv4 处理。
+func AddrToUint32(addr netip.Addr) (uint32, error) {
+	if !addr.IsValid() {
+		return 0, fmt.Errorf("%w: 地址为空", ErrInvalidIP)
+	}
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return 0, fmt.Errorf("%w: 当前qqwry.dat仅支持IPv4查询", ErrIPv6NotSupported)
+	}
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// FormatIPv4 将整数形式的 IPv4 还原为点分十进制字符串。
+func FormatIPv4(v uint32) string {
+	return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}).String()
+}
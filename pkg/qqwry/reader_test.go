@@ -0,0 +1,145 @@
+package qqwry
+
+import (
+	"errors"
+	"testing"
+
+	"ipservice/pkg/qqwry/qqwrytest"
+)
+
+// 第一条记录位于文件头之后，其国家字段从偏移 12 开始
+const firstLocation = headerLen + 4
+
+func sampleData() []byte {
+	return qqwrytest.Build(qqwrytest.Sample("2024年10月16日IP数据"))
+}
+
+// selfRedirect 返回第一条记录以指定模式重定向到自身的样例数据。
+func selfRedirect(mode byte) []byte {
+	data := sampleData()
+	data[firstLocation] = mode
+	data[firstLocation+1] = firstLocation
+	data[firstLocation+2] = 0
+	data[firstLocation+3] = 0
+	return data
+}
+
+func fuzzSeeds() [][]byte {
+	data := sampleData()
+	return [][]byte{
+		data,
+		data[:len(data)/2],
+		data[:len(data)-1],
+		data[:headerLen],
+		data[:headerLen-1],
+		selfRedirect(redirectMode1),
+		selfRedirect(redirectMode2),
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r, err := FromBytes(sampleData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := r.Lookup("1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Country != "江苏省苏州市" || rec.Area != "移动" || rec.StartIP() != "1.2.2.0" || rec.EndIP() != "1.2.127.255" {
+		t.Fatalf("Lookup(1.2.3.4) = %+v", rec)
+	}
+	// CZ88.NET 占位文本归一化为空
+	if rec, _ := r.Lookup("1.0.4.1"); rec.Country != "澳大利亚墨尔本" || rec.Area != "" {
+		t.Fatalf("Lookup(1.0.4.1) = %+v", rec)
+	}
+	if _, err := r.Lookup("::1"); !errors.Is(err, ErrIPv6NotSupported) {
+		t.Fatalf("Lookup(::1) err = %v", err)
+	}
+	if _, err := r.Lookup("1.2.3"); !errors.Is(err, ErrInvalidIP) {
+		t.Fatalf("Lookup(1.2.3) err = %v", err)
+	}
+	if got := r.Metadata(); got.Version != "2024年10月16日IP数据" || got.Records != 22 {
+		t.Fatalf("Metadata() = %+v", got)
+	}
+}
+
+func TestRedirectLoop(t *testing.T) {
+	for _, mode := range []byte{redirectMode1, redirectMode2} {
+		data := selfRedirect(mode)
+		if _, err := FromBytes(data); !errors.Is(err, ErrCorruptData) {
+			t.Fatalf("模式 %d 自重定向: FromBytes err = %v，期望 ErrCorruptData", mode, err)
+		}
+		r := headerOnly(t, data)
+		if _, _, err := r.readRecord(headerLen); !errors.Is(err, ErrCorruptData) {
+			t.Fatalf("模式 %d 自重定向: readRecord err = %v，期望 ErrCorruptData", mode, err)
+		}
+	}
+}
+
+// headerOnly 仅解析文件头创建 Reader，不做逐条校验，便于直接读取损坏的记录。
+func headerOnly(t *testing.T, data []byte) *Reader {
+	t.Helper()
+	indexStart, total, err := parseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Reader{data: data, indexStart: indexStart, total: total}
+}
+
+// FuzzFromBytes 确保任意输入要么被拒绝，要么得到可完整查询与遍历的 Reader，不会 panic。
+func FuzzFromBytes(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := FromBytes(data)
+		if err != nil {
+			return
+		}
+		it := r.Iterate()
+		n := 0
+		for it.Next() {
+			rec := it.Record()
+			got, err := r.LookupUint32(rec.Start)
+			if err != nil {
+				t.Fatalf("LookupUint32(%s) err = %v", rec.StartIP(), err)
+			}
+			if got != rec {
+				t.Fatalf("LookupUint32(%s) = %+v，遍历得到 %+v", rec.StartIP(), got, rec)
+			}
+			n++
+		}
+		if err := it.Err(); err != nil {
+			t.Fatalf("校验通过的数据遍历失败: %v", err)
+		}
+		if n != r.Metadata().Records {
+			t.Fatalf("遍历 %d 条，Metadata 记录数 %d", n, r.Metadata().Records)
+		}
+	})
+}
+
+// FuzzReadRecord 在未经校验的数据上从任意偏移读取记录，结果只能是错误或字段内容，重定向层数受限不会无限递归。
+func FuzzReadRecord(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed, uint32(headerLen))
+		f.Add(seed, uint32(firstLocation))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, offset uint32) {
+		indexStart, total, err := parseHeader(data)
+		if err != nil {
+			return
+		}
+		r := &Reader{data: data, indexStart: indexStart, total: total}
+		country, area, err := r.readRecord(offset)
+		if err != nil {
+			if !errors.Is(err, ErrCorruptData) {
+				t.Fatalf("readRecord(%d) err = %v，期望 ErrCorruptData", offset, err)
+			}
+			return
+		}
+		if len(country) > len(data) || len(area) > len(data) {
+			t.Fatalf("readRecord(%d) 返回的字段超出数据长度", offset)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package qqwry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// validate 逐条检查索引与记录：记录偏移与重定向目标需落在记录区内、字符串须正确终止、
+// 区段起止有序且互不重叠。
+func (r *Reader) validate() error {
+	report := &ValidationError{}
+	var prevEnd uint32
+	for i := uint32(0); i < r.total; i++ {
+		start, end, recordOffset, err := r.entry(i)
+		if err != nil {
+			if !report.add(err) {
+				break
+			}
+			continue
+		}
+		entryOffset := r.indexStart + i*indexEntryLen
+		if end < start {
+			if !report.add(corruptf(recordOffset, "第 %d 条记录结束地址 %s 小于起始地址 %s", i, FormatIPv4(end), FormatIPv4(start))) {
+				break
+			}
+		}
+		if i > 0 && start <= prevEnd {
+			if !report.add(corruptf(entryOffset, "第 %d 条索引起始地址 %s 未排序或与上一区段（止于 %s）重叠", i, FormatIPv4(start), FormatIPv4(prevEnd))) {
+				break
+			}
+		}
+		if _, _, err := r.readRecord(recordOffset); err != nil {
+			var issue *CorruptError
+			if errors.As(err, &issue) {
+				err = &CorruptError{Offset: issue.Offset, Message: fmt.Sprintf("第 %d 条记录: %s", i, issue.Message)}
+			}
+			if !report.add(err) {
+				break
+			}
+		}
+		prevEnd = end
+	}
+
+	if len(report.Issues) > 0 {
+		return report
+	}
+	return nil
+}